@@ -0,0 +1,50 @@
+package jwt
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// CertificateBindingValidator returns a Validator accepting only tokens bound to the client certificate of the
+// call's mutual TLS connection (RFC 8705): the token's cnf.x5t#S256 claim must equal the base64url encoded
+// SHA-256 thumbprint of the client certificate. Mismatches are rejected with codes.Unauthenticated.
+func CertificateBindingValidator() Validator {
+	return ValidatorFunc(func(ctx context.Context, token *jwt.Token) error {
+		cnf, _ := claimsMap(token.Claims)["cnf"].(map[string]any)
+		want, _ := cnf["x5t#S256"].(string)
+		if want == "" {
+			return status.Error(codes.Unauthenticated, "invalid token: missing required claim cnf.x5t#S256")
+		}
+		got, ok := clientCertificateThumbprint(ctx)
+		if !ok {
+			return status.Error(codes.Unauthenticated, "invalid token: certificate binding requires a client certificate")
+		}
+		if subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+			return status.Error(codes.Unauthenticated, "invalid token: certificate binding mismatch")
+		}
+		return nil
+	})
+}
+
+// clientCertificateThumbprint returns the x5t#S256 thumbprint of the client certificate of the call's TLS
+// connection.
+func clientCertificateThumbprint(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.PeerCertificates) == 0 {
+		return "", false
+	}
+	sum := sha256.Sum256(tlsInfo.State.PeerCertificates[0].Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), true
+}
@@ -0,0 +1,148 @@
+package jwt_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"log"
+	"net"
+	"testing"
+
+	"github.com/ErenDursun/go-grpc-jwt-middleware/jwt"
+	extJwt "github.com/golang-jwt/jwt/v5"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type CertificateBindingTestSuite struct {
+	suite.Suite
+
+	secret      []byte
+	clientCert  *x509.Certificate
+	otherCert   *x509.Certificate
+	client      grpc_health_v1.HealthClient
+	otherClient grpc_health_v1.HealthClient
+}
+
+func (s *CertificateBindingTestSuite) SetupSuite() {
+	s.secret = []byte("good_secret")
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{SigningKey: s.secret, RequireCertificateBinding: true})
+
+	certPEM, keyPEM, err := generateCertAndKey([]string{"localhost"})
+	require.NoError(s.T(), err)
+	serverCert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(s.T(), err)
+	serverCAs := x509.NewCertPool()
+	require.True(s.T(), serverCAs.AppendCertsFromPEM(certPEM))
+
+	clientCA, clientCAKey := newCACertificate("Test Client CA", nil, nil)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCA)
+	var clientKey, otherKey *ecdsa.PrivateKey
+	s.clientCert, clientKey = newCertificate(&x509.Certificate{Subject: pkix.Name{CommonName: "client"}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}, clientCA, clientCAKey)
+	s.otherCert, otherKey = newCertificate(&x509.Certificate{Subject: pkix.Name{CommonName: "other"}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}, clientCA, clientCAKey)
+
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(auth.UnaryServerInterceptor(authFunc)),
+		grpc.Creds(credentials.NewTLS(&tls.Config{
+			Certificates: []tls.Certificate{serverCert},
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			ClientCAs:    clientCAs,
+		})),
+	)
+	grpc_health_v1.RegisterHealthServer(srv, &assertingServer{assertFunc: func(ctx context.Context) {}})
+
+	const bufSize = 1024 * 1024
+	lis := bufconn.Listen(bufSize)
+	go func() {
+		if err := srv.Serve(lis); err != nil {
+			log.Fatalf("Server exited with error: %v", err)
+		}
+	}()
+	s.T().Cleanup(srv.Stop)
+
+	newClient := func(cert *x509.Certificate, key *ecdsa.PrivateKey) grpc_health_v1.HealthClient {
+		creds := credentials.NewTLS(&tls.Config{
+			ServerName:   "localhost",
+			RootCAs:      serverCAs,
+			Certificates: []tls.Certificate{{Certificate: [][]byte{cert.Raw}, PrivateKey: key}},
+		})
+		conn, err := grpc.NewClient("localhost",
+			grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+			grpc.WithTransportCredentials(creds),
+		)
+		require.NoError(s.T(), err)
+		s.T().Cleanup(func() { _ = conn.Close() })
+		return grpc_health_v1.NewHealthClient(conn)
+	}
+	s.client = newClient(s.clientCert, clientKey)
+	s.otherClient = newClient(s.otherCert, otherKey)
+}
+
+func TestCertificateBindingTestSuite(t *testing.T) {
+	suite.Run(t, new(CertificateBindingTestSuite))
+}
+
+// boundToken returns a token bound to the given certificate.
+func (s *CertificateBindingTestSuite) boundToken(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{
+		"sub": "client",
+		"cnf": map[string]any{"x5t#S256": base64.RawURLEncoding.EncodeToString(sum[:])},
+	}, s.secret)
+}
+
+func (s *CertificateBindingTestSuite) TestBoundToken() {
+	_, err := s.client.Check(ctxWithToken(context.TODO(), "bearer", s.boundToken(s.clientCert)), &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(s.T(), err, "no error must occur")
+}
+
+func (s *CertificateBindingTestSuite) TestReplayedFromOtherClient() {
+	_, err := s.otherClient.Check(ctxWithToken(context.TODO(), "bearer", s.boundToken(s.clientCert)), &grpc_health_v1.HealthCheckRequest{})
+
+	assert.Equal(s.T(), codes.Unauthenticated, status.Code(err), "must error with unauthenticated")
+	assert.Equal(s.T(), "invalid token: certificate binding mismatch", status.Convert(err).Message())
+}
+
+func (s *CertificateBindingTestSuite) TestUnboundToken() {
+	token := newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{"sub": "client"}, s.secret)
+
+	_, err := s.client.Check(ctxWithToken(context.TODO(), "bearer", token), &grpc_health_v1.HealthCheckRequest{})
+
+	assert.Equal(s.T(), codes.Unauthenticated, status.Code(err), "must error with unauthenticated")
+	assert.Equal(s.T(), "invalid token: missing required claim cnf.x5t#S256", status.Convert(err).Message())
+}
+
+func (s *CertificateBindingTestSuite) TestWithoutTLS() {
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{SigningKey: s.secret, RequireCertificateBinding: true})
+
+	_, err := authFunc(incomingCtxWithToken(context.TODO(), "Bearer", s.boundToken(s.clientCert)))
+
+	assert.Equal(s.T(), "invalid token: certificate binding requires a client certificate", status.Convert(err).Message())
+}
+
+func (s *CertificateBindingTestSuite) TestCustomParseTokenFunc() {
+	// e.g. introspector.ParseTokenFunc
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{
+		ParseTokenFunc: func(ctx context.Context, auth string) (any, error) {
+			return &extJwt.Token{Raw: auth, Claims: extJwt.MapClaims{"sub": "client"}, Valid: true}, nil
+		},
+		RequireCertificateBinding: true,
+	})
+
+	_, err := authFunc(incomingCtxWithToken(context.TODO(), "Bearer", "opaque"))
+
+	assert.Equal(s.T(), "invalid token: missing required claim cnf.x5t#S256", status.Convert(err).Message())
+}
@@ -0,0 +1,98 @@
+package jwt
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// claimsMap returns the claims as generic JSON object, converting custom claims types via their JSON encoding.
+func claimsMap(claims jwt.Claims) map[string]any {
+	switch c := claims.(type) {
+	case jwt.MapClaims:
+		return c
+	case *jwt.MapClaims:
+		return *c
+	}
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return nil
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil
+	}
+	return m
+}
+
+// Scopes returns the scopes granted by the claims, read from the space-delimited scope claim (RFC 8693)
+// or the scp array claim.
+func Scopes(claims jwt.Claims) []string {
+	return scopesFromClaims(claimsMap(claims))
+}
+
+// Roles returns the roles granted by the claims at the given paths. Nested claims are separated by dots,
+// e.g. "realm_access.roles". A claim may hold an array or a space-delimited string.
+func Roles(claims jwt.Claims, paths ...string) []string {
+	return rolesFromClaims(claimsMap(claims), paths)
+}
+
+func scopesFromClaims(claims map[string]any) []string {
+	scopes := stringsFromClaim(claims["scope"])
+	return append(scopes, stringsFromClaim(claims["scp"])...)
+}
+
+func rolesFromClaims(claims map[string]any, paths []string) []string {
+	var roles []string
+	for _, path := range paths {
+		roles = append(roles, stringsFromClaim(claimAtPath(claims, path))...)
+	}
+	return roles
+}
+
+// claimAtPath returns the claim at a dot-separated path of nested objects.
+func claimAtPath(claims map[string]any, path string) any {
+	var value any = claims
+	for name := range strings.SplitSeq(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = object[name]
+	}
+	return value
+}
+
+// stringsFromClaim returns the strings of an array claim or the fields of a space-delimited string claim.
+func stringsFromClaim(claim any) []string {
+	switch c := claim.(type) {
+	case string:
+		return strings.Fields(c)
+	case []string:
+		return c
+	case []any:
+		values := make([]string, 0, len(c))
+		for _, v := range c {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// claimMatches reports whether the claim value equals want or, for array claims, contains it.
+// A nil want only requires presence of the claim.
+func claimMatches(claim any, want any) bool {
+	if want == nil || reflect.DeepEqual(claim, want) {
+		return true
+	}
+	if values, ok := claim.([]any); ok {
+		return slices.ContainsFunc(values, func(v any) bool { return reflect.DeepEqual(v, want) })
+	}
+	return false
+}
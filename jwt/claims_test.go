@@ -0,0 +1,35 @@
+package jwt_test
+
+import (
+	"testing"
+
+	"github.com/ErenDursun/go-grpc-jwt-middleware/jwt"
+	extJwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type customClaims struct {
+	extJwt.RegisteredClaims
+
+	Scope string   `json:"scope"`
+	Roles []string `json:"roles"`
+}
+
+func TestScopes(t *testing.T) {
+	assert.Equal(t, []string{"a", "b"}, jwt.Scopes(extJwt.MapClaims{"scope": "a  b"}))
+	assert.Equal(t, []string{"a", "b"}, jwt.Scopes(extJwt.MapClaims{"scp": []any{"a", "b"}}))
+	assert.Equal(t, []string{"a"}, jwt.Scopes(&customClaims{Scope: "a"}))
+	assert.Empty(t, jwt.Scopes(extJwt.MapClaims{}))
+}
+
+func TestRoles(t *testing.T) {
+	claims := extJwt.MapClaims{
+		"roles":           []any{"a"},
+		"realm_access":    map[string]any{"roles": []any{"b", "c"}},
+		"resource_access": map[string]any{"api": map[string]any{"roles": "d e"}},
+	}
+
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, jwt.Roles(claims, "roles", "realm_access.roles", "resource_access.api.roles"))
+	assert.Empty(t, jwt.Roles(claims, "roles.nested", "missing"))
+	assert.Equal(t, []string{"x"}, jwt.Roles(&customClaims{Roles: []string{"x"}}, "roles"))
+}
@@ -0,0 +1,104 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minHMACSecretSizes holds the minimum secret size per HMAC algorithm, which is the size of the hash output as
+// required by RFC 7518 section 3.2.
+var minHMACSecretSizes = map[string]int{
+	jwt.SigningMethodHS256.Name: 32,
+	jwt.SigningMethodHS384.Name: 48,
+	jwt.SigningMethodHS512.Name: 64,
+}
+
+// Validate reports incomplete or inconsistent configs, which NewAuthFuncWithConfig would otherwise only surface
+// as rejected tokens:
+//   - no token validation key without InsecureSkipSignatureVerification, or both,
+//   - unknown signing methods or the "none" algorithm in SigningMethods,
+//   - keys that don't match any of the SigningMethods, e.g. an *ecdsa.PublicKey for HS256,
+//   - HMAC secrets shorter than the hash output of their algorithm.
+//
+// Keys supplied by a user-defined KeyFunc or a KeySource can't be checked in advance.
+// Key checks are skipped if a custom ParseTokenFunc is set.
+func (config Config) Validate() error {
+	if config.Leeway < 0 {
+		return errors.New("jwt config: negative leeway")
+	}
+	if config.MaxTokenLifetime < 0 {
+		return errors.New("jwt config: negative max token lifetime")
+	}
+	if config.ParseTokenFunc != nil {
+		return nil
+	}
+	if config.InsecureSkipSignatureVerification {
+		if config.hasKeyMaterial() {
+			return errors.New("jwt config: InsecureSkipSignatureVerification must not be combined with a token validation key")
+		}
+		return nil
+	}
+	if !config.hasKeyMaterial() {
+		return errors.New("jwt config: missing token validation key")
+	}
+	dynamicKeys := config.KeyFunc != nil || config.KeySource != nil
+	config.setDefaults()
+	for _, alg := range config.SigningMethods {
+		if alg == jwt.SigningMethodNone.Alg() {
+			return errors.New("jwt config: signing method none is not allowed")
+		}
+		if jwt.GetSigningMethod(alg) == nil {
+			return fmt.Errorf("jwt config: unknown signing method=%v", alg)
+		}
+	}
+	if dynamicKeys {
+		return nil
+	}
+	if len(config.SigningKeys) > 0 {
+		for kid, key := range config.SigningKeys {
+			if err := config.validateKey(key); err != nil {
+				return fmt.Errorf("jwt config: signing key with kid=%v: %w", kid, err)
+			}
+		}
+		return nil
+	}
+	if err := config.validateKey(config.SigningKey); err != nil {
+		return fmt.Errorf("jwt config: signing key: %w", err)
+	}
+	return nil
+}
+
+// validateKey makes sure the key can be used with at least one of the SigningMethods.
+func (config *Config) validateKey(key any) error {
+	vk := asVerificationKey(key)
+	if vk.Key == nil {
+		return errors.New("missing key")
+	}
+	algorithms := config.SigningMethods
+	if len(vk.Algorithms) > 0 {
+		algorithms = slices.DeleteFunc(slices.Clone(vk.Algorithms), func(alg string) bool {
+			return !slices.Contains(config.SigningMethods, alg)
+		})
+	}
+	if len(algorithms) == 0 {
+		return fmt.Errorf("key is not usable with any of the signing methods=%v", strings.Join(config.SigningMethods, ","))
+	}
+	var errs []error
+	for _, alg := range algorithms {
+		if err := checkKeyType(alg, vk.Key); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if size, ok := minHMACSecretSizes[alg]; ok && len(vk.Key.([]byte)) < size {
+			return fmt.Errorf("hmac secret for jwt signing method=%v must be at least %v bytes", alg, size)
+		}
+	}
+	if len(errs) == len(algorithms) {
+		return errors.Join(errs...)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package jwt_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/ErenDursun/go-grpc-jwt-middleware/jwt"
+	extJwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type ConfigTestSuite struct {
+	suite.Suite
+
+	secret []byte
+	ecKey  *ecdsa.PrivateKey
+}
+
+func (s *ConfigTestSuite) SetupSuite() {
+	s.secret = []byte("a_secret_of_at_least_thirty_two_bytes")
+	s.ecKey, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+}
+
+func TestConfigTestSuite(t *testing.T) {
+	suite.Run(t, new(ConfigTestSuite))
+}
+
+func (s *ConfigTestSuite) TestValidate_Valid() {
+	for name, config := range map[string]jwt.Config{
+		"hmac":     {SigningKey: s.secret},
+		"ecdsa":    {SigningKey: &s.ecKey.PublicKey, SigningMethods: []string{extJwt.SigningMethodES256.Name}},
+		"keys":     {SigningKeys: map[string]any{"hs": s.secret, "es": &s.ecKey.PublicKey}, SigningMethods: []string{extJwt.SigningMethodHS256.Name, extJwt.SigningMethodES256.Name}},
+		"eddsa":    {SigningKey: ed25519.PublicKey(make([]byte, ed25519.PublicKeySize)), SigningMethods: []string{extJwt.SigningMethodEdDSA.Alg()}},
+		"keyFunc":  {KeyFunc: func(*extJwt.Token) (any, error) { return s.secret, nil }},
+		"insecure": {InsecureSkipSignatureVerification: true},
+		"custom":   {ParseTokenFunc: func(context.Context, string) (any, error) { return nil, nil }},
+	} {
+		assert.NoError(s.T(), config.Validate(), name)
+	}
+}
+
+func (s *ConfigTestSuite) TestValidate_Invalid() {
+	for name, config := range map[string]jwt.Config{
+		"empty":            {},
+		"insecureWithKey":  {SigningKey: s.secret, InsecureSkipSignatureVerification: true},
+		"none":             {SigningKey: s.secret, SigningMethods: []string{extJwt.SigningMethodHS256.Name, "none"}},
+		"unknownMethod":    {SigningKey: s.secret, SigningMethods: []string{"XY256"}},
+		"hmacWithEcdsa":    {SigningKey: &s.ecKey.PublicKey},
+		"ecdsaWithSecret":  {SigningKey: s.secret, SigningMethods: []string{extJwt.SigningMethodES256.Name}},
+		"ecdsaPrivateKey":  {SigningKey: s.ecKey, SigningMethods: []string{extJwt.SigningMethodES256.Name}},
+		"ecdsaCurve":       {SigningKey: &s.ecKey.PublicKey, SigningMethods: []string{extJwt.SigningMethodES384.Name}},
+		"eddsaPrivateKey":  {SigningKey: ed25519.PrivateKey(make([]byte, ed25519.PrivateKeySize)), SigningMethods: []string{extJwt.SigningMethodEdDSA.Alg()}},
+		"eddsaWithEcdsa":   {SigningKey: &s.ecKey.PublicKey, SigningMethods: []string{extJwt.SigningMethodEdDSA.Alg()}},
+		"shortSecret":      {SigningKey: []byte("good_secret")},
+		"shortSecretHS512": {SigningKey: s.secret, SigningMethods: []string{extJwt.SigningMethodHS256.Name, extJwt.SigningMethodHS512.Name}},
+		"nilKeyInKeys":     {SigningKeys: map[string]any{"a": nil}},
+		"keyAlgorithms":    {SigningKey: jwt.VerificationKey{Key: s.secret, Algorithms: []string{extJwt.SigningMethodHS384.Name}}},
+	} {
+		assert.Error(s.T(), config.Validate(), name)
+	}
+}
+
+func (s *ConfigTestSuite) TestNewAuthFuncWithValidatedConfig() {
+	_, err := jwt.NewAuthFuncWithValidatedConfig(jwt.Config{})
+	assert.Error(s.T(), err, "there must be an error")
+
+	authFunc, err := jwt.NewAuthFuncWithValidatedConfig(jwt.Config{SigningKey: s.secret})
+	require.NoError(s.T(), err)
+	_, err = authFunc(incomingCtxWithToken(context.TODO(), "Bearer", newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{}, s.secret)))
+	require.NoError(s.T(), err, "no error must occur")
+}
+
+func (s *ConfigTestSuite) TestFailClosedWithoutKey() {
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{})
+
+	_, err := authFunc(incomingCtxWithToken(context.TODO(), "Bearer", newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{}, s.secret)))
+
+	assert.Error(s.T(), err, "there must be an error")
+	assert.Equal(s.T(), codes.Unauthenticated, status.Code(err), "must error with unauthenticated")
+}
+
+func (s *ConfigTestSuite) TestInsecureSkipSignatureVerification() {
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{InsecureSkipSignatureVerification: true})
+
+	_, err := authFunc(incomingCtxWithToken(context.TODO(), "Bearer", newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{}, []byte("any"))))
+
+	require.NoError(s.T(), err, "no error must occur")
+}
@@ -0,0 +1,67 @@
+package jwt
+
+import (
+	"context"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// AuthInfo is the typed value the middleware stores in the context of an authenticated call, in addition to the
+// untyped value stored under the Config's ContextKey.
+type AuthInfo struct {
+	// Token returned by ParseTokenFunc.
+	// Nil if a custom ParseTokenFunc returned a value of another type.
+	Token *jwt.Token
+
+	// Value returned by ParseTokenFunc.
+	Value any
+}
+
+// authInfoContextKey stores the AuthInfo independent of the configurable ContextKey.
+type authInfoContextKey struct{}
+
+func newContextWithAuthInfo(c context.Context, value any) context.Context {
+	info := &AuthInfo{Value: value}
+	info.Token, _ = value.(*jwt.Token)
+	return context.WithValue(c, authInfoContextKey{}, info)
+}
+
+// AuthInfoFromContext returns the AuthInfo of an authenticated call.
+func AuthInfoFromContext(c context.Context) (*AuthInfo, bool) {
+	info, ok := c.Value(authInfoContextKey{}).(*AuthInfo)
+	return info, ok
+}
+
+// TokenFromContext returns the token of an authenticated call, regardless of the Config's ContextKey.
+func TokenFromContext(c context.Context) (*jwt.Token, bool) {
+	info, ok := AuthInfoFromContext(c)
+	if !ok || info.Token == nil {
+		return nil, false
+	}
+	return info.Token, true
+}
+
+// ClaimsFromContext returns the claims of an authenticated call's token as type T, which is the type returned
+// by the Config's NewClaimsFunc, e.g. jwt.MapClaims or a pointer to a custom claims struct.
+func ClaimsFromContext[T jwt.Claims](c context.Context) (T, bool) {
+	token, ok := TokenFromContext(c)
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	claims, ok := token.Claims.(T)
+	return claims, ok
+}
+
+// SubjectFromContext returns the sub claim of an authenticated call's token.
+func SubjectFromContext(c context.Context) (string, bool) {
+	token, ok := TokenFromContext(c)
+	if !ok {
+		return "", false
+	}
+	sub, err := token.Claims.GetSubject()
+	if err != nil || sub == "" {
+		return "", false
+	}
+	return sub, true
+}
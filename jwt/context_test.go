@@ -0,0 +1,88 @@
+package jwt_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ErenDursun/go-grpc-jwt-middleware/jwt"
+	extJwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type ContextTestSuite struct {
+	suite.Suite
+
+	secret []byte
+}
+
+func (s *ContextTestSuite) SetupSuite() {
+	s.secret = []byte("good_secret")
+}
+
+func TestContextTestSuite(t *testing.T) {
+	suite.Run(t, new(ContextTestSuite))
+}
+
+func (s *ContextTestSuite) TestMapClaims() {
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{SigningKey: s.secret, ContextKey: "custom"})
+	token := newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{"sub": "alice"}, s.secret)
+
+	ctx, err := authFunc(incomingCtxWithToken(context.TODO(), "Bearer", token))
+	require.NoError(s.T(), err)
+
+	parsed, ok := jwt.TokenFromContext(ctx)
+	require.True(s.T(), ok)
+	assert.Same(s.T(), ctx.Value(jwt.ContextKey("custom")), parsed, "token must be the value stored under ContextKey")
+	claims, ok := jwt.ClaimsFromContext[extJwt.MapClaims](ctx)
+	require.True(s.T(), ok)
+	assert.Equal(s.T(), "alice", claims["sub"])
+	sub, ok := jwt.SubjectFromContext(ctx)
+	require.True(s.T(), ok)
+	assert.Equal(s.T(), "alice", sub)
+	_, ok = jwt.ClaimsFromContext[*extJwt.RegisteredClaims](ctx)
+	assert.False(s.T(), ok, "claims of another type must not be returned")
+}
+
+func (s *ContextTestSuite) TestCustomClaims() {
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{
+		SigningKey:    s.secret,
+		NewClaimsFunc: func(context.Context) extJwt.Claims { return &customClaims{} },
+	})
+	token := newSignedToken(extJwt.SigningMethodHS256, &customClaims{Scope: "a b", RegisteredClaims: extJwt.RegisteredClaims{Subject: "bob"}}, s.secret)
+
+	ctx, err := authFunc(incomingCtxWithToken(context.TODO(), "Bearer", token))
+	require.NoError(s.T(), err)
+
+	claims, ok := jwt.ClaimsFromContext[*customClaims](ctx)
+	require.True(s.T(), ok)
+	assert.Equal(s.T(), "a b", claims.Scope)
+	sub, ok := jwt.SubjectFromContext(ctx)
+	require.True(s.T(), ok)
+	assert.Equal(s.T(), "bob", sub)
+}
+
+func (s *ContextTestSuite) TestCustomParseTokenFunc() {
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{
+		ParseTokenFunc: func(context.Context, string) (any, error) { return "opaque", nil },
+	})
+
+	ctx, err := authFunc(incomingCtxWithToken(context.TODO(), "Bearer", "token"))
+	require.NoError(s.T(), err)
+
+	info, ok := jwt.AuthInfoFromContext(ctx)
+	require.True(s.T(), ok)
+	assert.Equal(s.T(), "opaque", info.Value)
+	_, ok = jwt.TokenFromContext(ctx)
+	assert.False(s.T(), ok)
+}
+
+func (s *ContextTestSuite) TestUnauthenticated() {
+	_, ok := jwt.TokenFromContext(context.TODO())
+	assert.False(s.T(), ok)
+	_, ok = jwt.ClaimsFromContext[extJwt.MapClaims](context.TODO())
+	assert.False(s.T(), ok)
+	_, ok = jwt.SubjectFromContext(context.TODO())
+	assert.False(s.T(), ok)
+}
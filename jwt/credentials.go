@@ -0,0 +1,155 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc/credentials"
+)
+
+// SignedTokenCredentialsConfig defines the config for client credentials signing their own tokens.
+type SignedTokenCredentialsConfig struct {
+	// SigningMethod used to sign the tokens.
+	// Required.
+	SigningMethod jwt.SigningMethod
+
+	// SigningKey used to sign the tokens, e.g. an *ecdsa.PrivateKey for ES256.
+	// Required.
+	SigningKey any
+
+	// KeyID set as kid header of the tokens.
+	// Optional.
+	KeyID string
+
+	// Claims template copied into every token, e.g. iss and sub. The aud, iat and exp claims are set per token.
+	// Optional.
+	Claims jwt.MapClaims
+
+	// PerMethodAudience sets the aud claim to the URI of the called method, e.g.
+	// "https://api.example.com/pkg.Service/Method", instead of the URI of the called service, e.g.
+	// "https://api.example.com/pkg.Service".
+	// Optional. Default value false.
+	PerMethodAudience bool
+
+	// Lifetime of the tokens.
+	// Optional. Default value 5 minutes.
+	Lifetime time.Duration
+
+	// RefreshBefore defines how long before expiry a cached token is replaced with a new one.
+	// Optional. Default value a tenth of Lifetime.
+	RefreshBefore time.Duration
+
+	// AuthScheme of the authorization metadata.
+	// Optional. Default value "Bearer".
+	AuthScheme string
+
+	// AllowInsecure allows sending tokens over connections without transport security. Anyone on the network can
+	// then steal and replay the tokens.
+	// Optional. Default value false.
+	AllowInsecure bool
+}
+
+// DefaultSignedTokenLifetime is the default lifetime of tokens signed by SignedTokenCredentials.
+const DefaultSignedTokenLifetime = 5 * time.Minute
+
+// SignedTokenCredentials implements credentials.PerRPCCredentials for services calling each other with self-signed
+// tokens. Tokens are cached per audience until shortly before they expire.
+type SignedTokenCredentials struct {
+	config SignedTokenCredentialsConfig
+
+	mu     sync.Mutex
+	tokens map[string]signedToken
+}
+
+type signedToken struct {
+	token     string
+	refreshAt time.Time
+}
+
+// NewSignedTokenCredentials returns SignedTokenCredentials for the given config.
+func NewSignedTokenCredentials(config SignedTokenCredentialsConfig) (*SignedTokenCredentials, error) {
+	if config.SigningMethod == nil {
+		return nil, errors.New("signed token credentials: missing signing method")
+	}
+	if config.SigningKey == nil {
+		return nil, errors.New("signed token credentials: missing signing key")
+	}
+	if config.Lifetime <= 0 {
+		config.Lifetime = DefaultSignedTokenLifetime
+	}
+	if config.RefreshBefore <= 0 || config.RefreshBefore >= config.Lifetime {
+		config.RefreshBefore = config.Lifetime / 10
+	}
+	if config.AuthScheme == "" {
+		config.AuthScheme = "Bearer"
+	}
+	return &SignedTokenCredentials{config: config, tokens: make(map[string]signedToken)}, nil
+}
+
+// GetRequestMetadata implements credentials.PerRPCCredentials.
+func (creds *SignedTokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	if len(uri) == 0 {
+		return nil, errors.New("signed token credentials: missing audience")
+	}
+	aud := uri[0]
+	if ri, ok := credentials.RequestInfoFromContext(ctx); ok {
+		if !creds.config.AllowInsecure {
+			if err := credentials.CheckSecurityLevel(ri.AuthInfo, credentials.PrivacyAndIntegrity); err != nil {
+				return nil, fmt.Errorf("signed token credentials: refusing to send token over insecure transport: %w", err)
+			}
+		}
+		if creds.config.PerMethodAudience {
+			aud += ri.Method[strings.LastIndex(ri.Method, "/"):]
+		}
+	}
+	token, err := creds.token(aud)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"authorization": creds.config.AuthScheme + " " + token}, nil
+}
+
+// RequireTransportSecurity implements credentials.PerRPCCredentials.
+func (creds *SignedTokenCredentials) RequireTransportSecurity() bool {
+	return !creds.config.AllowInsecure
+}
+
+// token returns the cached token for aud or signs a new one.
+func (creds *SignedTokenCredentials) token(aud string) (string, error) {
+	now := time.Now()
+	creds.mu.Lock()
+	defer creds.mu.Unlock()
+	if cached, ok := creds.tokens[aud]; ok && now.Before(cached.refreshAt) {
+		return cached.token, nil
+	}
+
+	claims := maps.Clone(creds.config.Claims)
+	if claims == nil {
+		claims = jwt.MapClaims{}
+	}
+	expiresAt := now.Add(creds.config.Lifetime)
+	claims["aud"] = aud
+	claims["iat"] = jwt.NewNumericDate(now)
+	claims["exp"] = jwt.NewNumericDate(expiresAt)
+	token := jwt.NewWithClaims(creds.config.SigningMethod, claims)
+	if creds.config.KeyID != "" {
+		token.Header["kid"] = creds.config.KeyID
+	}
+	signed, err := token.SignedString(creds.config.SigningKey)
+	if err != nil {
+		return "", fmt.Errorf("signed token credentials: %w", err)
+	}
+	for cachedAud, cached := range creds.tokens {
+		if !now.Before(cached.refreshAt) {
+			delete(creds.tokens, cachedAud)
+		}
+	}
+	creds.tokens[aud] = signedToken{token: signed, refreshAt: expiresAt.Add(-creds.config.RefreshBefore)}
+	return signed, nil
+}
@@ -0,0 +1,183 @@
+package jwt_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"log"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ErenDursun/go-grpc-jwt-middleware/jwt"
+	extJwt "github.com/golang-jwt/jwt/v5"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeAuthInfo implements a fake credentials.AuthInfo of a connection with the given security level.
+type fakeAuthInfo struct {
+	credentials.CommonAuthInfo
+}
+
+func (fakeAuthInfo) AuthType() string {
+	return "fake"
+}
+
+type SignedTokenCredentialsTestSuite struct {
+	suite.Suite
+
+	key            *ecdsa.PrivateKey
+	audiences      chan []string
+	bufDialer      func(context.Context, string) (net.Conn, error)
+	clientTLSCreds credentials.TransportCredentials
+}
+
+func (s *SignedTokenCredentialsTestSuite) SetupSuite() {
+	s.key, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.audiences = make(chan []string, 16)
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{
+		SigningMethods: []string{extJwt.SigningMethodES256.Name},
+		SigningKeys:    map[string]any{"client": &s.key.PublicKey},
+		Issuers:        []string{"client-service"},
+		Audiences:      []string{"https://localhost/grpc.health.v1.Health", "https://localhost/grpc.health.v1.Health/Check"},
+	})
+
+	certPEM, keyPEM, err := generateCertAndKey([]string{"localhost"})
+	require.NoError(s.T(), err)
+	cp := x509.NewCertPool()
+	require.True(s.T(), cp.AppendCertsFromPEM(certPEM))
+	s.clientTLSCreds = credentials.NewTLS(&tls.Config{ServerName: "localhost", RootCAs: cp})
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(s.T(), err)
+
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(auth.UnaryServerInterceptor(authFunc)),
+		grpc.Creds(credentials.NewServerTLSFromCert(&cert)),
+	)
+	grpc_health_v1.RegisterHealthServer(srv, &assertingServer{assertFunc: func(ctx context.Context) {
+		token, _ := jwt.TokenFromContext(ctx)
+		aud, _ := token.Claims.GetAudience()
+		s.audiences <- aud
+	}})
+
+	const bufSize = 1024 * 1024
+	lis := bufconn.Listen(bufSize)
+	go func() {
+		if err := srv.Serve(lis); err != nil {
+			log.Fatalf("Server exited with error: %v", err)
+		}
+	}()
+	s.T().Cleanup(srv.Stop)
+
+	s.bufDialer = func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}
+}
+
+func TestSignedTokenCredentialsTestSuite(t *testing.T) {
+	suite.Run(t, new(SignedTokenCredentialsTestSuite))
+}
+
+func (s *SignedTokenCredentialsTestSuite) newCredentials(change func(config *jwt.SignedTokenCredentialsConfig)) *jwt.SignedTokenCredentials {
+	config := jwt.SignedTokenCredentialsConfig{
+		SigningMethod: extJwt.SigningMethodES256,
+		SigningKey:    s.key,
+		KeyID:         "client",
+		Claims:        extJwt.MapClaims{"iss": "client-service", "sub": "client-service"},
+	}
+	if change != nil {
+		change(&config)
+	}
+	creds, err := jwt.NewSignedTokenCredentials(config)
+	require.NoError(s.T(), err)
+	return creds
+}
+
+func (s *SignedTokenCredentialsTestSuite) newClient(creds credentials.PerRPCCredentials, transportCreds credentials.TransportCredentials) grpc_health_v1.HealthClient {
+	conn, err := grpc.NewClient("localhost",
+		grpc.WithContextDialer(s.bufDialer),
+		grpc.WithTransportCredentials(transportCreds),
+		grpc.WithPerRPCCredentials(creds),
+	)
+	require.NoError(s.T(), err)
+	s.T().Cleanup(func() { _ = conn.Close() })
+	return grpc_health_v1.NewHealthClient(conn)
+}
+
+func (s *SignedTokenCredentialsTestSuite) TestServiceAudience() {
+	client := s.newClient(s.newCredentials(nil), s.clientTLSCreds)
+
+	_, err := client.Check(context.TODO(), &grpc_health_v1.HealthCheckRequest{})
+
+	require.NoError(s.T(), err, "no error must occur")
+	assert.Equal(s.T(), []string{"https://localhost/grpc.health.v1.Health"}, <-s.audiences)
+}
+
+func (s *SignedTokenCredentialsTestSuite) TestMethodAudience() {
+	client := s.newClient(s.newCredentials(func(config *jwt.SignedTokenCredentialsConfig) {
+		config.PerMethodAudience = true
+	}), s.clientTLSCreds)
+
+	_, err := client.Check(context.TODO(), &grpc_health_v1.HealthCheckRequest{})
+
+	require.NoError(s.T(), err, "no error must occur")
+	assert.Equal(s.T(), []string{"https://localhost/grpc.health.v1.Health/Check"}, <-s.audiences)
+}
+
+func (s *SignedTokenCredentialsTestSuite) TestInsecureTransport() {
+	_, err := grpc.NewClient("localhost",
+		grpc.WithContextDialer(s.bufDialer),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithPerRPCCredentials(s.newCredentials(nil)),
+	)
+	assert.Error(s.T(), err, "tokens must not be sent over insecure transport")
+
+	creds := s.newCredentials(nil)
+	ctx := credentials.NewContextWithRequestInfo(context.TODO(), credentials.RequestInfo{
+		Method:   "/grpc.health.v1.Health/Check",
+		AuthInfo: fakeAuthInfo{CommonAuthInfo: credentials.CommonAuthInfo{SecurityLevel: credentials.NoSecurity}},
+	})
+	_, err = creds.GetRequestMetadata(ctx, "http://localhost/grpc.health.v1.Health")
+	assert.Error(s.T(), err, "tokens must not be sent over insecure transport")
+
+	creds = s.newCredentials(func(config *jwt.SignedTokenCredentialsConfig) {
+		config.AllowInsecure = true
+	})
+	_, err = creds.GetRequestMetadata(ctx, "http://localhost/grpc.health.v1.Health")
+	assert.NoError(s.T(), err, "insecure transport must be allowed explicitly")
+}
+
+func (s *SignedTokenCredentialsTestSuite) TestCache() {
+	creds := s.newCredentials(func(config *jwt.SignedTokenCredentialsConfig) {
+		config.Lifetime = 2 * time.Second
+		config.RefreshBefore = 1500 * time.Millisecond
+	})
+
+	first, err := creds.GetRequestMetadata(context.TODO(), "https://localhost/a.Service")
+	require.NoError(s.T(), err)
+	second, err := creds.GetRequestMetadata(context.TODO(), "https://localhost/a.Service")
+	require.NoError(s.T(), err)
+	other, err := creds.GetRequestMetadata(context.TODO(), "https://localhost/b.Service")
+	require.NoError(s.T(), err)
+
+	assert.True(s.T(), strings.HasPrefix(first["authorization"], "Bearer "))
+	assert.Equal(s.T(), first, second, "token must be cached")
+	assert.NotEqual(s.T(), first, other, "tokens must be cached per audience")
+
+	time.Sleep(600 * time.Millisecond)
+	third, err := creds.GetRequestMetadata(context.TODO(), "https://localhost/a.Service")
+	require.NoError(s.T(), err)
+	assert.NotEqual(s.T(), first, third, "token must be replaced shortly before expiry")
+}
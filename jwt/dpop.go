@@ -0,0 +1,225 @@
+package jwt
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// DPoPConfig defines the config for sender-constrained tokens with DPoP proofs (RFC 9449).
+//
+// Calls using the "DPoP" auth scheme must carry a proof JWT in the "dpop" metadata. The proof must be signed with
+// the key of its jwk header, whose RFC 7638 thumbprint must equal the access token's cnf.jkt claim. Its htm claim must
+// be "POST", and its htu claim the call's full method name, e.g. "/pkg.Service/Method", or an absolute URI with the
+// full method name as path. Its ath claim must hold the hash of the access token, and each jti is only accepted once.
+// Tokens carrying a cnf.jkt claim are rejected if presented with another auth scheme.
+type DPoPConfig struct {
+	// Required rejects calls not using the "DPoP" auth scheme.
+	// Optional. Default value false.
+	Required bool
+
+	// SigningMethods allowed for proofs. Symmetric algorithms and "none" are always rejected.
+	// Optional. Default value ES256, ES384, ES512, RS256, PS256 and EdDSA.
+	SigningMethods []string
+
+	// MaxAge of a proof, measured from its iat claim.
+	// Optional. Default value 1 minute.
+	MaxAge time.Duration
+
+	// Leeway to account for clock skew of proofs issued in the future.
+	// Optional. Default value 0.
+	Leeway time.Duration
+
+	// ReplayStore records the jti of accepted proofs.
+	// Optional. Defaults to a MemoryReplayStore.
+	ReplayStore ReplayStore
+}
+
+const (
+	// DPoPAuthScheme is the auth scheme of DPoP-bound access tokens.
+	DPoPAuthScheme = "DPoP"
+
+	// DefaultDPoPMaxAge is the default maximum age of a DPoP proof.
+	DefaultDPoPMaxAge = time.Minute
+)
+
+func (config *DPoPConfig) setDefaults() {
+	if len(config.SigningMethods) == 0 {
+		config.SigningMethods = []string{
+			jwt.SigningMethodES256.Name, jwt.SigningMethodES384.Name, jwt.SigningMethodES512.Name,
+			jwt.SigningMethodRS256.Name, jwt.SigningMethodPS256.Name, jwt.SigningMethodEdDSA.Alg(),
+		}
+	}
+	if config.MaxAge <= 0 {
+		config.MaxAge = DefaultDPoPMaxAge
+	}
+	if config.ReplayStore == nil {
+		config.ReplayStore = NewMemoryReplayStore(0)
+	}
+}
+
+// authFromMD returns the token of the authorization metadata and whether it uses the DPoP auth scheme.
+func (config *Config) authFromMD(c context.Context) (string, bool, error) {
+	if config.DPoP == nil {
+		token, err := auth.AuthFromMD(c, config.AuthScheme)
+		return token, false, err
+	}
+	if token, err := auth.AuthFromMD(c, DPoPAuthScheme); err == nil {
+		return token, true, nil
+	}
+	if config.DPoP.Required {
+		return "", false, status.Errorf(codes.Unauthenticated, "Request unauthenticated with %v", DPoPAuthScheme)
+	}
+	token, err := auth.AuthFromMD(c, config.AuthScheme)
+	return token, false, err
+}
+
+// checkDPoP verifies the DPoP proof of a DPoP-bound token, and makes sure DPoP-bound tokens are not used as bearer
+// tokens.
+func (config *Config) checkDPoP(c context.Context, accessToken string, value any, dpop bool) error {
+	if config.DPoP == nil {
+		return nil
+	}
+	token, ok := value.(*jwt.Token)
+	if !ok {
+		return status.Error(codes.Internal, "dpop requires a *jwt.Token")
+	}
+	cnf, _ := claimsMap(token.Claims)["cnf"].(map[string]any)
+	jkt, _ := cnf["jkt"].(string)
+	if !dpop {
+		if jkt != "" {
+			return status.Error(codes.Unauthenticated, "invalid token: dpop-bound token used without dpop proof")
+		}
+		return nil
+	}
+	if jkt == "" {
+		return status.Error(codes.Unauthenticated, "invalid token: missing required claim cnf.jkt")
+	}
+	if err := config.DPoP.verifyProof(c, accessToken, jkt); err != nil {
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		return status.Errorf(codes.Unauthenticated, "invalid dpop proof: %v", err)
+	}
+	return nil
+}
+
+// dpopProofClaims are the claims of a DPoP proof.
+type dpopProofClaims struct {
+	jwt.RegisteredClaims
+	HTM string `json:"htm"`
+	HTU string `json:"htu"`
+	ATH string `json:"ath"`
+}
+
+func (config *DPoPConfig) verifyProof(c context.Context, accessToken string, jkt string) error {
+	proofs := metadata.ValueFromIncomingContext(c, "dpop")
+	if len(proofs) != 1 {
+		return errors.New("expected exactly one proof")
+	}
+	var proofKey any
+	claims := &dpopProofClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods(config.SigningMethods), jwt.WithoutClaimsValidation())
+	_, err := parser.ParseWithClaims(proofs[0], claims, func(proof *jwt.Token) (any, error) {
+		key, err := dpopProofKey(proof)
+		if err != nil {
+			return nil, err
+		}
+		proofKey = key
+		return key, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	thumbprint, err := Thumbprint(proofKey)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare([]byte(thumbprint), []byte(jkt)) != 1 {
+		return errors.New("key does not match cnf.jkt")
+	}
+	if claims.HTM != "POST" {
+		return errors.New("unexpected htm")
+	}
+	if fullMethod, _ := grpc.Method(c); fullMethod == "" || !dpopHTUMatches(claims.HTU, fullMethod) {
+		return errors.New("unexpected htu")
+	}
+	ath := sha256.Sum256([]byte(accessToken))
+	if subtle.ConstantTimeCompare([]byte(claims.ATH), []byte(base64.RawURLEncoding.EncodeToString(ath[:]))) != 1 {
+		return errors.New("unexpected ath")
+	}
+	if claims.IssuedAt == nil {
+		return errors.New("missing iat")
+	}
+	now := time.Now()
+	if claims.IssuedAt.After(now.Add(config.Leeway)) || claims.IssuedAt.Before(now.Add(-config.MaxAge)) {
+		return errors.New("iat is not fresh")
+	}
+	if claims.ID == "" {
+		return errors.New("missing jti")
+	}
+	used, err := config.ReplayStore.MarkUsed(c, thumbprint+":"+claims.ID, claims.IssuedAt.Add(config.MaxAge+config.Leeway))
+	if err != nil {
+		return status.Error(codes.Unavailable, "dpop replay check failed")
+	}
+	if used {
+		return errors.New("already used")
+	}
+	return nil
+}
+
+// dpopProofKey returns the public key of the proof's jwk header.
+func dpopProofKey(proof *jwt.Token) (any, error) {
+	if typ, _ := proof.Header["typ"].(string); !strings.EqualFold(typ, "dpop+jwt") {
+		return nil, errors.New("unexpected typ")
+	}
+	header, ok := proof.Header["jwk"].(map[string]any)
+	if !ok {
+		return nil, errors.New("missing jwk")
+	}
+	if _, ok := header["d"]; ok {
+		return nil, errors.New("jwk must not contain a private key")
+	}
+	data, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	var jwk jsonWebKey
+	if err := json.Unmarshal(data, &jwk); err != nil {
+		return nil, err
+	}
+	if jwk.Kty == "oct" {
+		return nil, errors.New("symmetric jwk")
+	}
+	key, err := jwk.publicKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkKeyType(proof.Method.Alg(), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// dpopHTUMatches reports whether htu is the full method name or an absolute URI with the full method name as path.
+func dpopHTUMatches(htu string, fullMethod string) bool {
+	if htu == fullMethod {
+		return true
+	}
+	u, err := url.Parse(htu)
+	return err == nil && u.IsAbs() && u.Path == fullMethod && u.RawQuery == "" && u.Fragment == ""
+}
@@ -0,0 +1,153 @@
+package jwt_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ErenDursun/go-grpc-jwt-middleware/jwt"
+	extJwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const dpopTestMethod = "/test.Service/Get"
+
+type DPoPTestSuite struct {
+	suite.Suite
+
+	secret    []byte
+	proofKey  *ecdsa.PrivateKey
+	otherKey  *ecdsa.PrivateKey
+	jkt       string
+	boundJWT  string
+	bearerJWT string
+	authFunc  func(context.Context) (context.Context, error)
+	proofs    int
+}
+
+func (s *DPoPTestSuite) SetupTest() {
+	s.secret = []byte("good_secret")
+	s.proofKey, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.otherKey, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.jkt, _ = jwt.Thumbprint(&s.proofKey.PublicKey)
+	s.boundJWT = newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{"sub": "alice", "cnf": map[string]any{"jkt": s.jkt}}, s.secret)
+	s.bearerJWT = newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{"sub": "alice"}, s.secret)
+	s.authFunc = jwt.NewAuthFuncWithConfig(jwt.Config{SigningKey: s.secret, DPoP: &jwt.DPoPConfig{}})
+}
+
+func TestDPoPTestSuite(t *testing.T) {
+	suite.Run(t, new(DPoPTestSuite))
+}
+
+// newProof returns a DPoP proof signed with key, applying change to the default proof claims.
+func (s *DPoPTestSuite) newProof(key *ecdsa.PrivateKey, accessToken string, change func(claims extJwt.MapClaims)) string {
+	return s.newProofWithJWK(key, &key.PublicKey, accessToken, change)
+}
+
+// newProofWithJWK returns a DPoP proof signed with key, carrying jwkKey in its jwk header.
+func (s *DPoPTestSuite) newProofWithJWK(key *ecdsa.PrivateKey, jwkKey *ecdsa.PublicKey, accessToken string, change func(claims extJwt.MapClaims)) string {
+	s.proofs++
+	ath := sha256.Sum256([]byte(accessToken))
+	claims := extJwt.MapClaims{
+		"jti": fmt.Sprintf("proof-%d", s.proofs),
+		"htm": "POST",
+		"htu": dpopTestMethod,
+		"iat": float64(time.Now().Unix()),
+		"ath": base64.RawURLEncoding.EncodeToString(ath[:]),
+	}
+	if change != nil {
+		change(claims)
+	}
+	jwk := newJWK("", jwkKey)
+	delete(jwk, "kid")
+	proof := extJwt.NewWithClaims(extJwt.SigningMethodES256, claims)
+	proof.Header["typ"] = "dpop+jwt"
+	proof.Header["jwk"] = jwk
+	signedProof, _ := proof.SignedString(key)
+	return signedProof
+}
+
+func (s *DPoPTestSuite) call(scheme string, accessToken string, proofs ...string) error {
+	md := metadata.Pairs("authorization", scheme+" "+accessToken)
+	for _, proof := range proofs {
+		md.Append("dpop", proof)
+	}
+	ctx := grpc.NewContextWithServerTransportStream(context.TODO(), &fakeTransportStream{method: dpopTestMethod})
+	_, err := s.authFunc(metadata.NewIncomingContext(ctx, md))
+	return err
+}
+
+func (s *DPoPTestSuite) assertRejected(err error, msg string) {
+	assert.Equal(s.T(), codes.Unauthenticated, status.Code(err), msg)
+}
+
+func (s *DPoPTestSuite) TestValidProof() {
+	require.NoError(s.T(), s.call("DPoP", s.boundJWT, s.newProof(s.proofKey, s.boundJWT, nil)))
+
+	proof := s.newProof(s.proofKey, s.boundJWT, func(claims extJwt.MapClaims) {
+		claims["htu"] = "https://api.example.com" + dpopTestMethod
+	})
+	require.NoError(s.T(), s.call("DPoP", s.boundJWT, proof), "absolute htu must be accepted")
+}
+
+func (s *DPoPTestSuite) TestBearer() {
+	require.NoError(s.T(), s.call("Bearer", s.bearerJWT), "bearer tokens must be accepted unless dpop is required")
+
+	err := s.call("Bearer", s.boundJWT)
+	s.assertRejected(err, "dpop-bound token must not be used as bearer token")
+
+	s.authFunc = jwt.NewAuthFuncWithConfig(jwt.Config{SigningKey: s.secret, DPoP: &jwt.DPoPConfig{Required: true}})
+	s.assertRejected(s.call("Bearer", s.bearerJWT), "bearer tokens must be rejected if dpop is required")
+}
+
+func (s *DPoPTestSuite) TestInvalidProof() {
+	for name, proofs := range map[string][]string{
+		"missing":   nil,
+		"multiple":  {s.newProof(s.proofKey, s.boundJWT, nil), s.newProof(s.proofKey, s.boundJWT, nil)},
+		"otherKey":  {s.newProof(s.otherKey, s.boundJWT, nil)},
+		"htm":       {s.newProof(s.proofKey, s.boundJWT, func(c extJwt.MapClaims) { c["htm"] = "GET" })},
+		"htu":       {s.newProof(s.proofKey, s.boundJWT, func(c extJwt.MapClaims) { c["htu"] = "/test.Service/Delete" })},
+		"htuQuery":  {s.newProof(s.proofKey, s.boundJWT, func(c extJwt.MapClaims) { c["htu"] = "https://api.example.com" + dpopTestMethod + "?a=b" })},
+		"ath":       {s.newProof(s.proofKey, s.bearerJWT, nil)},
+		"stale":     {s.newProof(s.proofKey, s.boundJWT, func(c extJwt.MapClaims) { c["iat"] = float64(time.Now().Add(-2 * time.Minute).Unix()) })},
+		"future":    {s.newProof(s.proofKey, s.boundJWT, func(c extJwt.MapClaims) { c["iat"] = float64(time.Now().Add(time.Minute).Unix()) })},
+		"noJti":     {s.newProof(s.proofKey, s.boundJWT, func(c extJwt.MapClaims) { delete(c, "jti") })},
+		"malformed": {"not a proof"},
+	} {
+		s.assertRejected(s.call("DPoP", s.boundJWT, proofs...), name)
+	}
+}
+
+func (s *DPoPTestSuite) TestProofSignedWithOtherKey() {
+	forged := s.newProofWithJWK(s.otherKey, &s.proofKey.PublicKey, s.boundJWT, nil)
+
+	s.assertRejected(s.call("DPoP", s.boundJWT, forged), "signature must be verified with the jwk")
+}
+
+func (s *DPoPTestSuite) TestReplay() {
+	proof := s.newProof(s.proofKey, s.boundJWT, nil)
+
+	require.NoError(s.T(), s.call("DPoP", s.boundJWT, proof))
+	err := s.call("DPoP", s.boundJWT, proof)
+
+	s.assertRejected(err, "proof must be used only once")
+	assert.Equal(s.T(), "invalid dpop proof: already used", status.Convert(err).Message())
+}
+
+func (s *DPoPTestSuite) TestUnboundToken() {
+	err := s.call("DPoP", s.bearerJWT, s.newProof(s.proofKey, s.bearerJWT, nil))
+
+	assert.Equal(s.T(), "invalid token: missing required claim cnf.jkt", status.Convert(err).Message())
+}
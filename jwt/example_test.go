@@ -69,8 +69,8 @@ func ExampleNewAuthFuncWithConfig() {
 	// server
 	authFunc := jwt.NewAuthFuncWithConfig(
 		jwt.Config{
-			SigningMethod: extJwt.SigningMethodES256.Name,
-			SigningKey:    &key.PublicKey,
+			SigningMethods: []string{extJwt.SigningMethodES256.Name},
+			SigningKey:     &key.PublicKey,
 		},
 	)
 	svr := grpc.NewServer(
@@ -0,0 +1,224 @@
+package jwt
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TokenExchangeConfig defines the config for exchanging tokens at an OAuth 2.0 token exchange endpoint (RFC 8693).
+type TokenExchangeConfig struct {
+	// Endpoint URL of the token endpoint.
+	// Required.
+	Endpoint string
+
+	// ClientID used to authenticate at the token endpoint with HTTP Basic authentication.
+	// Optional.
+	ClientID string
+
+	// ClientSecret used to authenticate at the token endpoint with HTTP Basic authentication.
+	// Optional.
+	ClientSecret string
+
+	// HTTPClient used to call the token endpoint.
+	// Optional. Defaults to a client with a 10 second timeout.
+	HTTPClient *http.Client
+
+	// Audience returns the audience requested for calls of the given target and method, e.g. the backend's
+	// client id at the authorization server. The target is the client connection's, e.g. "dns:///orders:8080".
+	// Required.
+	Audience func(target string, fullMethod string) string
+
+	// Scopes requested for the exchanged tokens.
+	// Optional.
+	Scopes []string
+
+	// SubjectTokenType of the tokens being exchanged.
+	// Optional. Default value "urn:ietf:params:oauth:token-type:access_token".
+	SubjectTokenType string
+
+	// RequestedTokenType of the exchanged tokens.
+	// Optional. Default value "urn:ietf:params:oauth:token-type:access_token".
+	RequestedTokenType string
+
+	// RefreshBefore defines how long before expiry a cached exchanged token is replaced. Exchanged tokens without
+	// expires_in are not cached.
+	// Optional. Default value 30 seconds.
+	RefreshBefore time.Duration
+}
+
+const (
+	tokenExchangeGrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+	accessTokenType        = "urn:ietf:params:oauth:token-type:access_token"
+
+	// DefaultTokenExchangeRefreshBefore is the default time before expiry at which exchanged tokens are replaced.
+	DefaultTokenExchangeRefreshBefore = 30 * time.Second
+)
+
+// TokenExchanger exchanges the caller's token for tokens scoped to the audience of downstream calls.
+// Exchanged tokens are cached per subject token and audience until shortly before they expire, and concurrent
+// exchanges of the same subject token for the same audience share one request.
+type TokenExchanger struct {
+	config TokenExchangeConfig
+
+	mu       sync.Mutex
+	cache    *expiringMap[[sha256.Size]byte, string]
+	inflight map[[sha256.Size]byte]*tokenExchangeCall
+}
+
+// tokenExchangeCall is a request to the token endpoint shared by concurrent exchanges.
+type tokenExchangeCall struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+// tokenExchangeResponse is the JSON representation of a token endpoint response.
+type tokenExchangeResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Error       string `json:"error"`
+}
+
+// NewTokenExchanger returns a TokenExchanger for the given config.
+func NewTokenExchanger(config TokenExchangeConfig) (*TokenExchanger, error) {
+	if config.Endpoint == "" {
+		return nil, errors.New("token exchange: missing endpoint")
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if config.Audience == nil {
+		return nil, errors.New("token exchange: missing audience")
+	}
+	if config.SubjectTokenType == "" {
+		config.SubjectTokenType = accessTokenType
+	}
+	if config.RequestedTokenType == "" {
+		config.RequestedTokenType = accessTokenType
+	}
+	if config.RefreshBefore <= 0 {
+		config.RefreshBefore = DefaultTokenExchangeRefreshBefore
+	}
+	return &TokenExchanger{
+		config:   config,
+		cache:    newExpiringMap[[sha256.Size]byte, string](),
+		inflight: make(map[[sha256.Size]byte]*tokenExchangeCall),
+	}, nil
+}
+
+// Downscope implements RelayConfig.Downscope by exchanging the token for the audience of the call.
+func (exchanger *TokenExchanger) Downscope(ctx context.Context, token *jwt.Token, target string, fullMethod string) (string, error) {
+	return exchanger.Exchange(ctx, token.Raw, exchanger.config.Audience(target, fullMethod))
+}
+
+// UnaryClientInterceptor returns a new unary client interceptor attaching the caller's token, exchanged for the
+// audience of the call, to calls allowed by relay. See RelayConfig.
+func (exchanger *TokenExchanger) UnaryClientInterceptor(relay RelayConfig) grpc.UnaryClientInterceptor {
+	relay.Downscope = exchanger.Downscope
+	return relay.UnaryClientInterceptor()
+}
+
+// StreamClientInterceptor returns a new stream client interceptor attaching the caller's token, exchanged for the
+// audience of the call, to calls allowed by relay. See RelayConfig.
+func (exchanger *TokenExchanger) StreamClientInterceptor(relay RelayConfig) grpc.StreamClientInterceptor {
+	relay.Downscope = exchanger.Downscope
+	return relay.StreamClientInterceptor()
+}
+
+// Exchange returns a token for audience in exchange for subjectToken. Exchanges rejected by the token endpoint,
+// e.g. with invalid_target, fail with codes.PermissionDenied and other failures with codes.Unavailable.
+func (exchanger *TokenExchanger) Exchange(ctx context.Context, subjectToken string, audience string) (string, error) {
+	key := sha256.Sum256([]byte(subjectToken + "\x00" + audience))
+	exchanger.mu.Lock()
+	if token, ok := exchanger.cache.get(key, time.Now()); ok {
+		exchanger.mu.Unlock()
+		return token, nil
+	}
+	call, ok := exchanger.inflight[key]
+	if !ok {
+		call = &tokenExchangeCall{done: make(chan struct{})}
+		exchanger.inflight[key] = call
+		// the request outlives the caller's cancellation, as other callers may be waiting for it
+		go exchanger.run(context.WithoutCancel(ctx), key, call, subjectToken, audience)
+	}
+	exchanger.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.token, call.err
+	case <-ctx.Done():
+		return "", status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+// run exchanges the token on behalf of the exchanges waiting for call and caches the result.
+func (exchanger *TokenExchanger) run(ctx context.Context, key [sha256.Size]byte, call *tokenExchangeCall, subjectToken string, audience string) {
+	response, err := exchanger.exchange(ctx, subjectToken, audience)
+	now := time.Now()
+
+	exchanger.mu.Lock()
+	if err != nil {
+		call.err = err
+	} else {
+		call.token = response.AccessToken
+		if response.ExpiresIn > 0 {
+			exchanger.cache.set(key, response.AccessToken, now.Add(time.Duration(response.ExpiresIn)*time.Second-exchanger.config.RefreshBefore), now)
+		}
+	}
+	delete(exchanger.inflight, key)
+	exchanger.mu.Unlock()
+	close(call.done)
+}
+
+func (exchanger *TokenExchanger) exchange(ctx context.Context, subjectToken string, audience string) (*tokenExchangeResponse, error) {
+	form := url.Values{
+		"grant_type":           {tokenExchangeGrantType},
+		"subject_token":        {subjectToken},
+		"subject_token_type":   {exchanger.config.SubjectTokenType},
+		"requested_token_type": {exchanger.config.RequestedTokenType},
+		"audience":             {audience},
+	}
+	if len(exchanger.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(exchanger.config.Scopes, " "))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, exchanger.config.Endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, status.Error(codes.Internal, "token exchange failed")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if exchanger.config.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(exchanger.config.ClientID), url.QueryEscape(exchanger.config.ClientSecret))
+	}
+	resp, err := exchanger.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, "token exchange failed")
+	}
+	defer resp.Body.Close()
+	var response tokenExchangeResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&response); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, status.Error(codes.Unavailable, "token exchange failed: invalid response")
+	}
+	switch {
+	case resp.StatusCode == http.StatusBadRequest:
+		return nil, status.Errorf(codes.PermissionDenied, "token exchange rejected: %v", response.Error)
+	case resp.StatusCode != http.StatusOK:
+		return nil, status.Errorf(codes.Unavailable, "token exchange failed: unexpected status code=%v", resp.StatusCode)
+	case response.AccessToken == "":
+		return nil, status.Error(codes.Unavailable, "token exchange failed: missing access_token")
+	}
+	return &response, nil
+}
@@ -0,0 +1,269 @@
+package jwt_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ErenDursun/go-grpc-jwt-middleware/jwt"
+	extJwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type TokenExchangeTestSuite struct {
+	suite.Suite
+
+	secret    []byte
+	token     string
+	ctx       context.Context
+	server    *httptest.Server
+	requests  atomic.Int32
+	expiresIn int64
+	delay     atomic.Int64
+	received  chan []string
+	dialer    func(context.Context, string) (net.Conn, error)
+}
+
+func (s *TokenExchangeTestSuite) SetupSuite() {
+	s.secret = []byte("good_secret")
+	s.token = newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{"sub": "alice"}, s.secret)
+
+	// token endpoint issuing "<audience>:<subject token>" for audiences of the orders service
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		time.Sleep(time.Duration(s.delay.Load()))
+		w.Header().Set("Content-Type", "application/json")
+		if id, secret, ok := r.BasicAuth(); !ok || id != "gateway" || secret != "gateway_secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "invalid_client"})
+			return
+		}
+		audience := r.PostFormValue("audience")
+		if r.PostFormValue("grant_type") != "urn:ietf:params:oauth:grant-type:token-exchange" ||
+			r.PostFormValue("subject_token_type") != "urn:ietf:params:oauth:token-type:access_token" ||
+			r.PostFormValue("subject_token") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "invalid_request"})
+			return
+		}
+		if audience != "orders" && audience != "orders/grpc.health.v1.Health/Check" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "invalid_target"})
+			return
+		}
+		response := map[string]any{
+			"access_token":      audience + ":" + r.PostFormValue("subject_token"),
+			"issued_token_type": "urn:ietf:params:oauth:token-type:access_token",
+			"token_type":        "Bearer",
+		}
+		if expiresIn := atomic.LoadInt64(&s.expiresIn); expiresIn > 0 {
+			response["expires_in"] = expiresIn
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	s.T().Cleanup(s.server.Close)
+
+	s.received = make(chan []string, 16)
+	srv := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(srv, &assertingServer{assertFunc: func(ctx context.Context) {
+		s.received <- metadata.ValueFromIncomingContext(ctx, "authorization")
+	}})
+	lis := bufconn.Listen(1024 * 1024)
+	go func() { _ = srv.Serve(lis) }()
+	s.T().Cleanup(srv.Stop)
+	s.dialer = func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}
+}
+
+func (s *TokenExchangeTestSuite) SetupTest() {
+	s.requests.Store(0)
+	s.delay.Store(0)
+	atomic.StoreInt64(&s.expiresIn, 300)
+
+	authFunc := jwt.NewAuthFunc(s.secret)
+	ctx, err := authFunc(incomingCtxWithToken(context.TODO(), "Bearer", s.token))
+	require.NoError(s.T(), err)
+	s.ctx = ctx
+}
+
+func TestTokenExchangeTestSuite(t *testing.T) {
+	suite.Run(t, new(TokenExchangeTestSuite))
+}
+
+func (s *TokenExchangeTestSuite) newExchanger(config jwt.TokenExchangeConfig) *jwt.TokenExchanger {
+	config.Endpoint = s.server.URL
+	if config.Audience == nil {
+		config.Audience = func(target string, fullMethod string) string { return "orders" }
+	}
+	if config.ClientID == "" {
+		config.ClientID, config.ClientSecret = "gateway", "gateway_secret"
+	}
+	exchanger, err := jwt.NewTokenExchanger(config)
+	require.NoError(s.T(), err)
+	return exchanger
+}
+
+func (s *TokenExchangeTestSuite) TestMissingConfig() {
+	audience := func(target string, fullMethod string) string { return "orders" }
+	_, err := jwt.NewTokenExchanger(jwt.TokenExchangeConfig{Audience: audience})
+	assert.Error(s.T(), err, "endpoint must be required")
+
+	_, err = jwt.NewTokenExchanger(jwt.TokenExchangeConfig{Endpoint: s.server.URL})
+	assert.Error(s.T(), err, "audience must be required, as targets aren't audiences")
+}
+
+func (s *TokenExchangeTestSuite) TestExchange() {
+	exchanger := s.newExchanger(jwt.TokenExchangeConfig{})
+
+	token, err := exchanger.Exchange(context.TODO(), s.token, "orders")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "orders:"+s.token, token)
+}
+
+func (s *TokenExchangeTestSuite) TestCache() {
+	exchanger := s.newExchanger(jwt.TokenExchangeConfig{})
+
+	for i := 0; i < 3; i++ {
+		_, err := exchanger.Exchange(context.TODO(), s.token, "orders")
+		require.NoError(s.T(), err)
+	}
+	assert.Equal(s.T(), int32(1), s.requests.Load(), "exchanged tokens must be cached")
+
+	other := newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{"sub": "bob"}, s.secret)
+	token, err := exchanger.Exchange(context.TODO(), other, "orders")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "orders:"+other, token)
+	_, err = exchanger.Exchange(context.TODO(), s.token, "orders/grpc.health.v1.Health/Check")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), int32(3), s.requests.Load(), "the cache must be keyed by subject token and audience")
+}
+
+func (s *TokenExchangeTestSuite) TestConcurrentExchanges() {
+	s.delay.Store(int64(100 * time.Millisecond))
+	exchanger := s.newExchanger(jwt.TokenExchangeConfig{})
+
+	var wg sync.WaitGroup
+	for range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := exchanger.Exchange(context.TODO(), s.token, "orders")
+			assert.NoError(s.T(), err)
+			assert.Equal(s.T(), "orders:"+s.token, token)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(s.T(), int32(1), s.requests.Load(), "concurrent exchanges must share one request")
+}
+
+func (s *TokenExchangeTestSuite) TestCanceledExchange() {
+	s.delay.Store(int64(100 * time.Millisecond))
+	exchanger := s.newExchanger(jwt.TokenExchangeConfig{})
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := exchanger.Exchange(ctx, s.token, "orders")
+	assert.Equal(s.T(), codes.DeadlineExceeded, status.Code(err))
+
+	// the shared request isn't canceled with its first caller
+	token, err := exchanger.Exchange(context.TODO(), s.token, "orders")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "orders:"+s.token, token)
+	assert.Equal(s.T(), int32(1), s.requests.Load())
+}
+
+func (s *TokenExchangeTestSuite) TestCacheRefresh() {
+	// tokens expiring within RefreshBefore are not cached
+	atomic.StoreInt64(&s.expiresIn, 1)
+	exchanger := s.newExchanger(jwt.TokenExchangeConfig{RefreshBefore: time.Second})
+	for i := 0; i < 2; i++ {
+		_, err := exchanger.Exchange(context.TODO(), s.token, "orders")
+		require.NoError(s.T(), err)
+	}
+	assert.Equal(s.T(), int32(2), s.requests.Load())
+
+	// tokens without expires_in are not cached
+	atomic.StoreInt64(&s.expiresIn, 0)
+	exchanger = s.newExchanger(jwt.TokenExchangeConfig{})
+	for i := 0; i < 2; i++ {
+		_, err := exchanger.Exchange(context.TODO(), s.token, "orders")
+		require.NoError(s.T(), err)
+	}
+	assert.Equal(s.T(), int32(4), s.requests.Load())
+}
+
+func (s *TokenExchangeTestSuite) TestErrors() {
+	exchanger := s.newExchanger(jwt.TokenExchangeConfig{})
+	_, err := exchanger.Exchange(context.TODO(), s.token, "payments")
+	assert.Equal(s.T(), codes.PermissionDenied, status.Code(err))
+	assert.Contains(s.T(), status.Convert(err).Message(), "invalid_target")
+
+	exchanger = s.newExchanger(jwt.TokenExchangeConfig{ClientID: "gateway", ClientSecret: "bad_secret"})
+	_, err = exchanger.Exchange(context.TODO(), s.token, "orders")
+	assert.Equal(s.T(), codes.Unavailable, status.Code(err), "misconfigured clients must not be reported as rejected tokens")
+
+	exchanger, err = jwt.NewTokenExchanger(jwt.TokenExchangeConfig{Endpoint: "http://127.0.0.1:0", Audience: func(string, string) string { return "orders" }})
+	require.NoError(s.T(), err)
+	_, err = exchanger.Exchange(context.TODO(), s.token, "orders")
+	assert.Equal(s.T(), codes.Unavailable, status.Code(err))
+}
+
+func (s *TokenExchangeTestSuite) newClient(exchanger *jwt.TokenExchanger) grpc_health_v1.HealthClient {
+	relay := jwt.RelayConfig{Targets: []string{"passthrough:///orders"}}
+	conn, err := grpc.NewClient("passthrough:///orders",
+		grpc.WithContextDialer(s.dialer),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(exchanger.UnaryClientInterceptor(relay)),
+		grpc.WithStreamInterceptor(exchanger.StreamClientInterceptor(relay)),
+	)
+	require.NoError(s.T(), err)
+	s.T().Cleanup(func() { _ = conn.Close() })
+	return grpc_health_v1.NewHealthClient(conn)
+}
+
+func (s *TokenExchangeTestSuite) TestInterceptors() {
+	client := s.newClient(s.newExchanger(jwt.TokenExchangeConfig{
+		Audience: func(target string, fullMethod string) string { return "orders" },
+	}))
+
+	_, err := client.Check(s.ctx, &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"Bearer orders:" + s.token}, <-s.received)
+
+	stream, err := client.Watch(s.ctx, &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(s.T(), err)
+	_, err = stream.Recv()
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"Bearer orders:" + s.token}, <-s.received)
+	assert.Equal(s.T(), int32(1), s.requests.Load())
+}
+
+func (s *TokenExchangeTestSuite) TestInterceptorPerMethodAudience() {
+	client := s.newClient(s.newExchanger(jwt.TokenExchangeConfig{
+		Audience: func(target string, fullMethod string) string { return "orders" + fullMethod },
+	}))
+
+	_, err := client.Check(s.ctx, &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"Bearer orders/grpc.health.v1.Health/Check:" + s.token}, <-s.received)
+
+	_, err = client.Watch(s.ctx, &grpc_health_v1.HealthCheckRequest{})
+	assert.Equal(s.T(), codes.PermissionDenied, status.Code(err), "calls must fail if the exchange is rejected")
+}
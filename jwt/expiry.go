@@ -0,0 +1,83 @@
+package jwt
+
+import (
+	"container/heap"
+	"time"
+)
+
+// expiringMap is a map whose entries are dropped once they expire. Expiries are kept in a min-heap, so that
+// dropping expired entries costs O(log n) per entry instead of a scan of the whole map.
+// It is not safe for concurrent use, callers guard it with their own lock.
+type expiringMap[K comparable, V any] struct {
+	entries  map[K]expiringEntry[V]
+	expiries expiryHeap[K]
+}
+
+type expiringEntry[V any] struct {
+	value V
+	until time.Time
+}
+
+func newExpiringMap[K comparable, V any]() *expiringMap[K, V] {
+	return &expiringMap[K, V]{entries: make(map[K]expiringEntry[V])}
+}
+
+// get returns the value of key if it hasn't expired at now. It doesn't modify the map, so it may be called under
+// a read lock.
+func (m *expiringMap[K, V]) get(key K, now time.Time) (V, bool) {
+	entry, ok := m.entries[key]
+	if !ok || !now.Before(entry.until) {
+		var zero V
+		return zero, false
+	}
+	return entry.value, true
+}
+
+// set stores value under key until until, replacing a previous entry, and drops the entries expired at now.
+func (m *expiringMap[K, V]) set(key K, value V, until time.Time, now time.Time) {
+	m.removeExpired(now)
+	if !now.Before(until) {
+		delete(m.entries, key)
+		return
+	}
+	m.entries[key] = expiringEntry[V]{value: value, until: until}
+	heap.Push(&m.expiries, expiry[K]{key: key, until: until})
+}
+
+// removeExpired drops the entries expired at now.
+func (m *expiringMap[K, V]) removeExpired(now time.Time) {
+	for len(m.expiries) > 0 && !now.Before(m.expiries[0].until) {
+		expired := heap.Pop(&m.expiries).(expiry[K])
+		// the key may have been set again since, with another expiry
+		if entry, ok := m.entries[expired.key]; ok && entry.until.Equal(expired.until) {
+			delete(m.entries, expired.key)
+		}
+	}
+}
+
+// len returns the number of entries not expired at now.
+func (m *expiringMap[K, V]) len(now time.Time) int {
+	m.removeExpired(now)
+	return len(m.entries)
+}
+
+type expiry[K comparable] struct {
+	key   K
+	until time.Time
+}
+
+// expiryHeap implements heap.Interface ordered by expiry.
+type expiryHeap[K comparable] []expiry[K]
+
+func (h expiryHeap[K]) Len() int           { return len(h) }
+func (h expiryHeap[K]) Less(i, j int) bool { return h[i].until.Before(h[j].until) }
+func (h expiryHeap[K]) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *expiryHeap[K]) Push(x any)        { *h = append(*h, x.(expiry[K])) }
+
+func (h *expiryHeap[K]) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
@@ -0,0 +1,124 @@
+package jwt
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// FileKeySourceConfig defines the config for verification keys read from files.
+type FileKeySourceConfig struct {
+	// Paths of the key files in any format supported by LoadKeys. The keys of all files are merged.
+	// Required.
+	Paths []string
+
+	// PollInterval defines how often the files are checked for changes.
+	// Optional. Default value 30 seconds.
+	PollInterval time.Duration
+
+	// ReloadErrorHandler is called when changed files fail to load. The previously loaded keys stay in use.
+	// Optional.
+	ReloadErrorHandler func(err error)
+}
+
+// DefaultFileKeySourcePollInterval is the default interval in which key files are checked for changes.
+const DefaultFileKeySourcePollInterval = 30 * time.Second
+
+// FileKeySource is a KeySource backed by key files that are reloaded when their content changes, e.g. keys mounted
+// from a Kubernetes secret rotating in place. Keys are looked up by the token's kid header. Tokens without kid are
+// validated with the only key, if exactly one key is loaded.
+type FileKeySource struct {
+	config FileKeySourceConfig
+	keys   KeySet
+
+	reloadMu sync.Mutex
+	loaded   bool
+	checksum [sha256.Size]byte
+}
+
+// NewFileKeySource loads the keys from config.Paths and keeps polling the files for changes until ctx is done.
+// An error is returned if the initial load fails.
+func NewFileKeySource(ctx context.Context, config FileKeySourceConfig) (*FileKeySource, error) {
+	if len(config.Paths) == 0 {
+		return nil, errors.New("key files: missing paths")
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultFileKeySourcePollInterval
+	}
+	source := &FileKeySource{config: config}
+	if err := source.Reload(); err != nil {
+		return nil, err
+	}
+	go source.pollLoop(ctx)
+	return source, nil
+}
+
+// Reload reads the key files and swaps the loaded keys if the files' content changed.
+// The loaded keys are kept if reading or parsing fails.
+func (source *FileKeySource) Reload() error {
+	source.reloadMu.Lock()
+	defer source.reloadMu.Unlock()
+
+	contents := make([][]byte, len(source.config.Paths))
+	hash := sha256.New()
+	for i, path := range source.config.Paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("key files: %w", err)
+		}
+		contents[i] = data
+		fmt.Fprintf(hash, "%d:", len(data))
+		hash.Write(data)
+	}
+	var checksum [sha256.Size]byte
+	hash.Sum(checksum[:0])
+	if source.loaded && checksum == source.checksum {
+		return nil
+	}
+
+	keys := make(map[string]any)
+	for i, data := range contents {
+		fileKeys, err := LoadKeys(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("key files: %v: %w", source.config.Paths[i], err)
+		}
+		maps.Copy(keys, fileKeys)
+	}
+	source.keys.Replace(keys)
+	source.loaded = true
+	source.checksum = checksum
+	return nil
+}
+
+// Keys returns a copy of the loaded keys.
+func (source *FileKeySource) Keys() map[string]any {
+	return source.keys.Snapshot()
+}
+
+// LookupKey returns the loaded key matching the token's kid header.
+func (source *FileKeySource) LookupKey(token *jwt.Token) (any, error) {
+	return source.keys.LookupKey(token)
+}
+
+func (source *FileKeySource) pollLoop(ctx context.Context) {
+	ticker := time.NewTicker(source.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := source.Reload(); err != nil && source.config.ReloadErrorHandler != nil {
+				source.config.ReloadErrorHandler(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package jwt_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ErenDursun/go-grpc-jwt-middleware/jwt"
+	extJwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type FileKeySourceTestSuite struct {
+	suite.Suite
+
+	dir    string
+	oldKey *ecdsa.PrivateKey
+	newKey *ecdsa.PrivateKey
+	claims extJwt.MapClaims
+}
+
+func (s *FileKeySourceTestSuite) SetupTest() {
+	s.dir = s.T().TempDir()
+	s.oldKey, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.newKey, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.claims = extJwt.MapClaims{"foo": "bar"}
+}
+
+func TestFileKeySourceTestSuite(t *testing.T) {
+	suite.Run(t, new(FileKeySourceTestSuite))
+}
+
+// writeFile replaces the file atomically, like a rotating Kubernetes secret.
+func (s *FileKeySourceTestSuite) writeFile(name string, data []byte) string {
+	path := filepath.Join(s.dir, name)
+	tmp := path + ".tmp"
+	require.NoError(s.T(), os.WriteFile(tmp, data, 0o600))
+	require.NoError(s.T(), os.Rename(tmp, path))
+	return path
+}
+
+func (s *FileKeySourceTestSuite) writeJWKS(name string, keys ...map[string]any) string {
+	data, _ := json.Marshal(map[string]any{"keys": keys})
+	return s.writeFile(name, data)
+}
+
+func (s *FileKeySourceTestSuite) auth(authFunc func(context.Context) (context.Context, error), token string) error {
+	_, err := authFunc(incomingCtxWithToken(context.TODO(), "Bearer", token))
+	return err
+}
+
+func (s *FileKeySourceTestSuite) TestReload() {
+	path := s.writeJWKS("jwks.json", newJWK("old", &s.oldKey.PublicKey))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	source, err := jwt.NewFileKeySource(ctx, jwt.FileKeySourceConfig{Paths: []string{path}, PollInterval: 10 * time.Millisecond})
+	require.NoError(s.T(), err)
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{KeySource: source, SigningMethods: []string{extJwt.SigningMethodES256.Name}})
+	oldToken := newSignedTokenWithKid(extJwt.SigningMethodES256, s.claims, "old", s.oldKey)
+	newToken := newSignedTokenWithKid(extJwt.SigningMethodES256, s.claims, "new", s.newKey)
+
+	require.NoError(s.T(), s.auth(authFunc, oldToken))
+	require.Error(s.T(), s.auth(authFunc, newToken))
+
+	s.writeJWKS("jwks.json", newJWK("new", &s.newKey.PublicKey))
+
+	assert.Eventually(s.T(), func() bool { return s.auth(authFunc, newToken) == nil }, time.Second, 10*time.Millisecond, "rotated key must be picked up")
+	assert.Error(s.T(), s.auth(authFunc, oldToken), "removed key must be dropped")
+}
+
+func (s *FileKeySourceTestSuite) TestReloadError() {
+	path := s.writeJWKS("jwks.json", newJWK("old", &s.oldKey.PublicKey))
+	var mu sync.Mutex
+	var reloadErr error
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	source, err := jwt.NewFileKeySource(ctx, jwt.FileKeySourceConfig{
+		Paths:        []string{path},
+		PollInterval: 10 * time.Millisecond,
+		ReloadErrorHandler: func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			reloadErr = err
+		},
+	})
+	require.NoError(s.T(), err)
+
+	s.writeFile("jwks.json", []byte(`{"keys": [`))
+
+	assert.Eventually(s.T(), func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return reloadErr != nil
+	}, time.Second, 10*time.Millisecond, "reload error must be reported")
+	assert.Contains(s.T(), source.Keys(), "old", "previous keys must be kept")
+}
+
+func (s *FileKeySourceTestSuite) TestMultipleFiles() {
+	der, _ := x509.MarshalPKIXPublicKey(&s.newKey.PublicKey)
+	pemPath := s.writeFile("key.pem", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	jwksPath := s.writeJWKS("jwks.json", newJWK("old", &s.oldKey.PublicKey))
+
+	source, err := jwt.NewFileKeySource(context.TODO(), jwt.FileKeySourceConfig{Paths: []string{pemPath, jwksPath}})
+	require.NoError(s.T(), err)
+
+	kid, _ := jwt.Thumbprint(&s.newKey.PublicKey)
+	assert.Len(s.T(), source.Keys(), 2)
+	assert.Contains(s.T(), source.Keys(), kid)
+	assert.Contains(s.T(), source.Keys(), "old")
+}
+
+func (s *FileKeySourceTestSuite) TestSingleKeyWithoutKid() {
+	der, _ := x509.MarshalPKIXPublicKey(&s.oldKey.PublicKey)
+	path := s.writeFile("key.pem", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	source, err := jwt.NewFileKeySource(context.TODO(), jwt.FileKeySourceConfig{Paths: []string{path}})
+	require.NoError(s.T(), err)
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{KeySource: source, SigningMethods: []string{extJwt.SigningMethodES256.Name}})
+
+	require.NoError(s.T(), s.auth(authFunc, newSignedToken(extJwt.SigningMethodES256, s.claims, s.oldKey)))
+}
+
+func (s *FileKeySourceTestSuite) TestInitialLoadError() {
+	_, err := jwt.NewFileKeySource(context.TODO(), jwt.FileKeySourceConfig{Paths: []string{filepath.Join(s.dir, "missing.pem")}})
+	assert.Error(s.T(), err)
+
+	_, err = jwt.NewFileKeySource(context.TODO(), jwt.FileKeySourceConfig{})
+	assert.Error(s.T(), err)
+}
@@ -0,0 +1,163 @@
+package jwt
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// IntrospectionConfig defines the config for validating tokens with an OAuth 2.0 token introspection endpoint
+// (RFC 7662).
+type IntrospectionConfig struct {
+	// Endpoint URL of the introspection endpoint.
+	// Required.
+	Endpoint string
+
+	// ClientID used to authenticate at the introspection endpoint with HTTP Basic authentication.
+	// Optional.
+	ClientID string
+
+	// ClientSecret used to authenticate at the introspection endpoint with HTTP Basic authentication.
+	// Optional.
+	ClientSecret string
+
+	// TokenTypeHint sent along with the token, e.g. "access_token".
+	// Optional.
+	TokenTypeHint string
+
+	// HTTPClient used to call the introspection endpoint.
+	// Optional. Defaults to a client with a 10 second timeout.
+	HTTPClient *http.Client
+
+	// MaxCacheDuration caps how long an active introspection result is cached. Results are never cached beyond
+	// the token's exp, and results of tokens without exp are only cached if MaxCacheDuration is set.
+	// Optional. Results are cached until the token's exp if zero. A negative value disables caching.
+	MaxCacheDuration time.Duration
+}
+
+// Introspector validates tokens, opaque or not, with an OAuth 2.0 token introspection endpoint and caches active
+// results until the token expires.
+type Introspector struct {
+	config IntrospectionConfig
+
+	mu    sync.Mutex
+	cache *expiringMap[[sha256.Size]byte, jwt.MapClaims]
+}
+
+// NewIntrospector returns an Introspector for the given config.
+func NewIntrospector(config IntrospectionConfig) (*Introspector, error) {
+	if config.Endpoint == "" {
+		return nil, errors.New("introspection: missing endpoint")
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Introspector{config: config, cache: newExpiringMap[[sha256.Size]byte, jwt.MapClaims]()}, nil
+}
+
+// ParseTokenFunc implements Config.ParseTokenFunc. It returns a *jwt.Token holding the introspection response
+// as jwt.MapClaims, without the active member. The token's Method is nil, as its signature is not checked locally.
+func (introspector *Introspector) ParseTokenFunc(c context.Context, auth string) (any, error) {
+	claims, err := introspector.Introspect(c, auth)
+	if err != nil {
+		return nil, err
+	}
+	return &jwt.Token{Raw: auth, Header: map[string]any{}, Claims: claims, Valid: true}, nil
+}
+
+// Introspect returns the claims of an active token. Inactive tokens are rejected with codes.Unauthenticated and
+// failures to reach the introspection endpoint with codes.Unavailable.
+// Each call returns its own claims map, but nested claim values are shared with the cache and must not be modified.
+func (introspector *Introspector) Introspect(c context.Context, token string) (jwt.MapClaims, error) {
+	key := sha256.Sum256([]byte(token))
+	if claims, ok := introspector.cached(key); ok {
+		return claims, nil
+	}
+	claims, err := introspector.introspect(c, token)
+	if err != nil {
+		return nil, err
+	}
+	introspector.store(key, claims)
+	return claims, nil
+}
+
+func (introspector *Introspector) introspect(c context.Context, token string) (jwt.MapClaims, error) {
+	form := url.Values{"token": {token}}
+	if introspector.config.TokenTypeHint != "" {
+		form.Set("token_type_hint", introspector.config.TokenTypeHint)
+	}
+	req, err := http.NewRequestWithContext(c, http.MethodPost, introspector.config.Endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, status.Error(codes.Internal, "token introspection failed")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if introspector.config.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(introspector.config.ClientID), url.QueryEscape(introspector.config.ClientSecret))
+	}
+	resp, err := introspector.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, "token introspection failed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, status.Errorf(codes.Unavailable, "token introspection failed: unexpected status code=%v", resp.StatusCode)
+	}
+	var claims jwt.MapClaims
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&claims); err != nil {
+		return nil, status.Error(codes.Unavailable, "token introspection failed: invalid response")
+	}
+	if active, _ := claims["active"].(bool); !active {
+		return nil, status.Error(codes.Unauthenticated, "invalid token: inactive")
+	}
+	delete(claims, "active")
+	if exp, err := claims.GetExpirationTime(); err != nil || exp != nil && !time.Now().Before(exp.Time) {
+		return nil, status.Error(codes.Unauthenticated, "invalid token: expired")
+	}
+	return claims, nil
+}
+
+func (introspector *Introspector) cached(key [sha256.Size]byte) (jwt.MapClaims, bool) {
+	introspector.mu.Lock()
+	defer introspector.mu.Unlock()
+	claims, ok := introspector.cache.get(key, time.Now())
+	if !ok {
+		return nil, false
+	}
+	return maps.Clone(claims), true
+}
+
+// store caches an active result until the token's exp, capped by MaxCacheDuration.
+func (introspector *Introspector) store(key [sha256.Size]byte, claims jwt.MapClaims) {
+	maxCacheDuration := introspector.config.MaxCacheDuration
+	if maxCacheDuration < 0 {
+		return
+	}
+	now := time.Now()
+	var until time.Time
+	if exp, _ := claims.GetExpirationTime(); exp != nil {
+		until = exp.Time
+	}
+	if maxCacheDuration > 0 && (until.IsZero() || now.Add(maxCacheDuration).Before(until)) {
+		until = now.Add(maxCacheDuration)
+	}
+	if until.IsZero() {
+		return
+	}
+
+	introspector.mu.Lock()
+	defer introspector.mu.Unlock()
+	introspector.cache.set(key, maps.Clone(claims), until, now)
+}
@@ -0,0 +1,176 @@
+package jwt_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ErenDursun/go-grpc-jwt-middleware/jwt"
+	extJwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// introspectionServer serves RFC 7662 introspection responses for a fixed set of tokens.
+type introspectionServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	tokens   map[string]map[string]any
+	requests atomic.Int32
+}
+
+func newIntrospectionServer() *introspectionServer {
+	server := &introspectionServer{tokens: make(map[string]map[string]any)}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.requests.Add(1)
+		if id, secret, ok := r.BasicAuth(); !ok || id != "api" || secret != "api_secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		server.mu.Lock()
+		response, ok := server.tokens[r.PostFormValue("token")]
+		server.mu.Unlock()
+		if !ok {
+			response = map[string]any{"active": false}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	return server
+}
+
+func (server *introspectionServer) set(token string, response map[string]any) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.tokens[token] = response
+}
+
+type IntrospectionTestSuite struct {
+	suite.Suite
+
+	server       *introspectionServer
+	introspector *jwt.Introspector
+	authFunc     func(context.Context) (context.Context, error)
+}
+
+func (s *IntrospectionTestSuite) SetupTest() {
+	s.server = newIntrospectionServer()
+	introspector, err := jwt.NewIntrospector(jwt.IntrospectionConfig{
+		Endpoint:     s.server.URL,
+		ClientID:     "api",
+		ClientSecret: "api_secret",
+	})
+	require.NoError(s.T(), err)
+	s.introspector = introspector
+	s.authFunc = jwt.NewAuthFuncWithConfig(jwt.Config{ParseTokenFunc: introspector.ParseTokenFunc})
+}
+
+func (s *IntrospectionTestSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func TestIntrospectionTestSuite(t *testing.T) {
+	suite.Run(t, new(IntrospectionTestSuite))
+}
+
+func (s *IntrospectionTestSuite) TestActive() {
+	s.server.set("opaque-1", map[string]any{
+		"active": true,
+		"sub":    "alice",
+		"scope":  "docs.read docs.write",
+		"exp":    time.Now().Add(time.Minute).Unix(),
+	})
+
+	ctx, err := s.authFunc(incomingCtxWithToken(context.TODO(), "Bearer", "opaque-1"))
+	require.NoError(s.T(), err)
+
+	sub, ok := jwt.SubjectFromContext(ctx)
+	assert.True(s.T(), ok)
+	assert.Equal(s.T(), "alice", sub)
+	claims, ok := jwt.ClaimsFromContext[extJwt.MapClaims](ctx)
+	require.True(s.T(), ok)
+	assert.Equal(s.T(), []string{"docs.read", "docs.write"}, jwt.Scopes(claims))
+	assert.NotContains(s.T(), claims, "active")
+}
+
+func (s *IntrospectionTestSuite) TestInactive() {
+	_, err := s.authFunc(incomingCtxWithToken(context.TODO(), "Bearer", "unknown"))
+	assert.Equal(s.T(), codes.Unauthenticated, status.Code(err), "must error with unauthenticated")
+	assert.Equal(s.T(), "invalid token: inactive", status.Convert(err).Message())
+
+	s.server.set("expired", map[string]any{"active": true, "exp": time.Now().Add(-time.Minute).Unix()})
+	_, err = s.authFunc(incomingCtxWithToken(context.TODO(), "Bearer", "expired"))
+	assert.Equal(s.T(), "invalid token: expired", status.Convert(err).Message())
+}
+
+func (s *IntrospectionTestSuite) TestCachesActiveResults() {
+	s.server.set("opaque-2", map[string]any{"active": true, "exp": time.Now().Add(time.Minute).Unix()})
+
+	for range 3 {
+		_, err := s.introspector.Introspect(context.TODO(), "opaque-2")
+		require.NoError(s.T(), err)
+	}
+	assert.Equal(s.T(), int32(1), s.server.requests.Load(), "active result must be cached")
+
+	for range 2 {
+		_, err := s.introspector.Introspect(context.TODO(), "unknown")
+		require.Error(s.T(), err)
+	}
+	assert.Equal(s.T(), int32(3), s.server.requests.Load(), "inactive result must not be cached")
+}
+
+func (s *IntrospectionTestSuite) TestCachedClaimsAreCopied() {
+	s.server.set("opaque-6", map[string]any{"active": true, "sub": "alice", "exp": time.Now().Add(time.Minute).Unix()})
+
+	claims, err := s.introspector.Introspect(context.TODO(), "opaque-6")
+	require.NoError(s.T(), err)
+	claims["sub"] = "mallory"
+	claims, err = s.introspector.Introspect(context.TODO(), "opaque-6")
+	require.NoError(s.T(), err)
+	claims["tenant"] = "other"
+
+	claims, err = s.introspector.Introspect(context.TODO(), "opaque-6")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "alice", claims["sub"], "changes to returned claims must not leak into the cache")
+	assert.NotContains(s.T(), claims, "tenant")
+	assert.Equal(s.T(), int32(1), s.server.requests.Load())
+}
+
+func (s *IntrospectionTestSuite) TestCacheExpiry() {
+	introspector, err := jwt.NewIntrospector(jwt.IntrospectionConfig{
+		Endpoint:         s.server.URL,
+		ClientID:         "api",
+		ClientSecret:     "api_secret",
+		MaxCacheDuration: 10 * time.Millisecond,
+	})
+	require.NoError(s.T(), err)
+	s.server.set("opaque-3", map[string]any{"active": true, "exp": time.Now().Add(time.Minute).Unix()})
+
+	_, err = introspector.Introspect(context.TODO(), "opaque-3")
+	require.NoError(s.T(), err)
+	time.Sleep(20 * time.Millisecond)
+	s.server.set("opaque-3", map[string]any{"active": false})
+
+	_, err = introspector.Introspect(context.TODO(), "opaque-3")
+	assert.Equal(s.T(), "invalid token: inactive", status.Convert(err).Message(), "result must be refetched after MaxCacheDuration")
+}
+
+func (s *IntrospectionTestSuite) TestEndpointFailure() {
+	introspector, err := jwt.NewIntrospector(jwt.IntrospectionConfig{Endpoint: s.server.URL, ClientID: "api", ClientSecret: "wrong"})
+	require.NoError(s.T(), err)
+
+	_, err = introspector.Introspect(context.TODO(), "opaque-1")
+	assert.Equal(s.T(), codes.Unavailable, status.Code(err), "must error with unavailable")
+
+	_, err = jwt.NewIntrospector(jwt.IntrospectionConfig{})
+	assert.Error(s.T(), err, "endpoint must be required")
+}
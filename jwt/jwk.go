@@ -0,0 +1,158 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// jsonWebKey is the JSON representation of a single key as defined in RFC 7517.
+type jsonWebKey struct {
+	Kty string   `json:"kty"`
+	Kid string   `json:"kid,omitempty"`
+	Use string   `json:"use,omitempty"`
+	Alg string   `json:"alg,omitempty"`
+	Crv string   `json:"crv,omitempty"`
+	N   string   `json:"n,omitempty"`
+	E   string   `json:"e,omitempty"`
+	X   string   `json:"x,omitempty"`
+	Y   string   `json:"y,omitempty"`
+	K   string   `json:"k,omitempty"`
+	X5c []string `json:"x5c,omitempty"`
+}
+
+// jsonWebKeySet is the JSON representation of a key set as defined in RFC 7517.
+// Keys are kept raw so that a single malformed or unsupported key does not invalidate the whole set.
+type jsonWebKeySet struct {
+	Keys []json.RawMessage `json:"keys"`
+}
+
+// parseJSONWebKeySet parses a JWKS document into verification keys indexed by kid.
+// Keys carrying an alg parameter are restricted to that algorithm.
+// Keys without kid, keys meant for encryption and keys of unsupported types are skipped.
+func parseJSONWebKeySet(data []byte) (map[string]any, error) {
+	var set jsonWebKeySet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid jwks document: %w", err)
+	}
+	if set.Keys == nil {
+		return nil, errors.New("invalid jwks document: missing keys")
+	}
+	keys := make(map[string]any, len(set.Keys))
+	for _, raw := range set.Keys {
+		var jwk jsonWebKey
+		if err := json.Unmarshal(raw, &jwk); err != nil {
+			continue
+		}
+		if jwk.Kid == "" || jwk.Use == "enc" {
+			continue
+		}
+		key, err := jwk.verificationKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
+
+// verificationKey returns the JWK's key, restricted to the JWK's alg if present.
+func (jwk *jsonWebKey) verificationKey() (any, error) {
+	key, err := jwk.publicKey()
+	if err != nil {
+		return nil, err
+	}
+	if jwk.Alg != "" {
+		return VerificationKey{Key: key, Algorithms: []string{jwk.Alg}}, nil
+	}
+	return key, nil
+}
+
+// publicKey returns the verification key described by the JWK.
+func (jwk *jsonWebKey) publicKey() (any, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwk modulus: %w", err)
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwk exponent: %w", err)
+		}
+		if n.Sign() <= 0 || !e.IsInt64() || e.Int64() < 2 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid jwk rsa parameters")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		curve, err := jwkCurve(jwk.Crv)
+		if err != nil {
+			return nil, err
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwk x coordinate: %w", err)
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwk y coordinate: %w", err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("invalid jwk ec point")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported jwk curve=%v", jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwk x coordinate: %w", err)
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid jwk ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	case "oct":
+		k, err := base64.RawURLEncoding.DecodeString(jwk.K)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwk secret: %w", err)
+		}
+		if len(k) == 0 {
+			return nil, errors.New("invalid jwk secret: empty")
+		}
+		return k, nil
+	default:
+		return nil, fmt.Errorf("unsupported jwk key type=%v", jwk.Kty)
+	}
+}
+
+func jwkCurve(crv string) (elliptic.Curve, error) {
+	switch crv {
+	case "P-256":
+		return elliptic.P256(), nil
+	case "P-384":
+		return elliptic.P384(), nil
+	case "P-521":
+		return elliptic.P521(), nil
+	default:
+		return nil, fmt.Errorf("unsupported jwk curve=%v", crv)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, errors.New("missing value")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
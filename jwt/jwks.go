@@ -0,0 +1,230 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// JWKSConfig defines the config for a remote JSON Web Key Set.
+type JWKSConfig struct {
+	// URL of the JSON Web Key Set document.
+	// Required.
+	URL string
+
+	// HTTPClient used to download the key set.
+	// Optional. Defaults to a client with a 10 second timeout.
+	HTTPClient *http.Client
+
+	// RefreshInterval defines how often the key set is downloaded again in the background.
+	// Optional. Default value 1 hour.
+	RefreshInterval time.Duration
+
+	// MinRefreshInterval limits how often a token with an unknown kid may trigger an on-demand download of the
+	// key set. Concurrent lookups of unknown kids share a single download.
+	// Optional. Default value 1 minute. A negative value disables on-demand downloads.
+	MinRefreshInterval time.Duration
+
+	// RefreshErrorHandler is called when a background refresh fails. The previously downloaded keys stay in use.
+	// Optional.
+	RefreshErrorHandler func(err error)
+}
+
+// JWKSStats holds the lookup and refresh counters of a JWKS.
+type JWKSStats struct {
+	// Hits counts lookups whose kid was found in the cached key set.
+	Hits uint64
+	// Misses counts lookups whose kid was not found in the cached key set, before any on-demand download.
+	Misses uint64
+	// Refreshes counts successful downloads of the key set, including the initial one.
+	Refreshes uint64
+}
+
+const (
+	// DefaultJWKSRefreshInterval is the default interval in which a JWKS is downloaded again.
+	DefaultJWKSRefreshInterval = time.Hour
+
+	// DefaultJWKSMinRefreshInterval is the default minimum interval between on-demand downloads of a JWKS.
+	DefaultJWKSMinRefreshInterval = time.Minute
+
+	// maxResponseSize limits the size of documents read from endpoints and key files, e.g. a key set or an
+	// introspection response.
+	maxResponseSize = 1 << 20
+)
+
+var errJWKSRefreshRateLimited = errors.New("jwks: on-demand refresh rate limited")
+
+// JWKS is a KeySource backed by a remote JSON Web Key Set.
+// RSA, EC, Ed25519 and oct keys are cached by kid and refreshed periodically.
+// Tokens with an unknown kid trigger a rate-limited on-demand download, so key rotations are picked up without
+// waiting for the next periodic refresh.
+type JWKS struct {
+	config JWKSConfig
+	ctx    context.Context
+
+	keys KeySet
+
+	refreshMu   sync.Mutex
+	lastRefresh time.Time
+	inflight    *jwksRefreshCall
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	refreshes atomic.Uint64
+}
+
+// jwksRefreshCall is an on-demand download shared by concurrent lookups of unknown kids.
+type jwksRefreshCall struct {
+	done chan struct{}
+	err  error
+}
+
+// NewJWKS downloads the key set from config.URL and keeps refreshing it until ctx is done.
+// An error is returned if the initial download fails.
+func NewJWKS(ctx context.Context, config JWKSConfig) (*JWKS, error) {
+	if config.URL == "" {
+		return nil, errors.New("jwks: missing url")
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if config.RefreshInterval <= 0 {
+		config.RefreshInterval = DefaultJWKSRefreshInterval
+	}
+	if config.MinRefreshInterval == 0 {
+		config.MinRefreshInterval = DefaultJWKSMinRefreshInterval
+	}
+	jwks := &JWKS{config: config, ctx: ctx}
+	if err := jwks.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	go jwks.refreshLoop(ctx)
+	return jwks, nil
+}
+
+// Refresh downloads the key set and replaces the cached keys.
+// The cached keys are kept if the download or parsing fails.
+func (jwks *JWKS) Refresh(ctx context.Context) error {
+	jwks.refreshMu.Lock()
+	jwks.lastRefresh = time.Now()
+	jwks.refreshMu.Unlock()
+
+	keys, err := jwks.fetch(ctx)
+	if err != nil {
+		return err
+	}
+	jwks.keys.Replace(keys)
+	jwks.refreshes.Add(1)
+	return nil
+}
+
+// LookupKey returns the cached key matching the token's kid header.
+// An unknown kid triggers an on-demand download of the key set, unless the last download happened less than
+// MinRefreshInterval ago.
+func (jwks *JWKS) LookupKey(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok, generation := jwks.keys.key(kid)
+	if ok {
+		jwks.hits.Add(1)
+		return key, nil
+	}
+	jwks.misses.Add(1)
+	if kid != "" && jwks.refreshOnMiss(generation) == nil {
+		if key, ok, _ := jwks.keys.key(kid); ok {
+			return key, nil
+		}
+	}
+	return nil, status.Errorf(codes.Unauthenticated, "unexpected jwt key id=%v", token.Header["kid"])
+}
+
+// Stats returns a snapshot of the lookup and refresh counters.
+func (jwks *JWKS) Stats() JWKSStats {
+	return JWKSStats{
+		Hits:      jwks.hits.Load(),
+		Misses:    jwks.misses.Load(),
+		Refreshes: jwks.refreshes.Load(),
+	}
+}
+
+// refreshOnMiss downloads the key set on behalf of a lookup of an unknown kid in the given key set generation.
+// Concurrent callers wait for the same download; callers within MinRefreshInterval of the last download fail fast.
+func (jwks *JWKS) refreshOnMiss(generation uint64) error {
+	if jwks.config.MinRefreshInterval < 0 {
+		return errJWKSRefreshRateLimited
+	}
+	jwks.refreshMu.Lock()
+	if call := jwks.inflight; call != nil {
+		jwks.refreshMu.Unlock()
+		<-call.done
+		return call.err
+	}
+	if jwks.keys.generation() != generation {
+		// the key set has been replaced since the lookup missed
+		jwks.refreshMu.Unlock()
+		return nil
+	}
+	if time.Since(jwks.lastRefresh) < jwks.config.MinRefreshInterval {
+		jwks.refreshMu.Unlock()
+		return errJWKSRefreshRateLimited
+	}
+	call := &jwksRefreshCall{done: make(chan struct{})}
+	jwks.inflight = call
+	jwks.refreshMu.Unlock()
+
+	call.err = jwks.Refresh(jwks.ctx)
+
+	jwks.refreshMu.Lock()
+	jwks.inflight = nil
+	jwks.refreshMu.Unlock()
+	close(call.done)
+	return call.err
+}
+
+func (jwks *JWKS) refreshLoop(ctx context.Context) {
+	ticker := time.NewTicker(jwks.config.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := jwks.Refresh(ctx); err != nil && jwks.config.RefreshErrorHandler != nil {
+				jwks.config.RefreshErrorHandler(err)
+			}
+		}
+	}
+}
+
+func (jwks *JWKS) fetch(ctx context.Context) (map[string]any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwks.config.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := jwks.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks: unexpected status code=%v from %v", resp.StatusCode, jwks.config.URL)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+	keys, err := parseJSONWebKeySet(data)
+	if err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+	return keys, nil
+}
@@ -0,0 +1,174 @@
+package jwt_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ErenDursun/go-grpc-jwt-middleware/jwt"
+	extJwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type JWKSTestSuite struct {
+	suite.Suite
+
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+	claims extJwt.MapClaims
+	server *jwksServer
+}
+
+func (s *JWKSTestSuite) SetupSuite() {
+	s.rsaKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+	s.ecKey, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.claims = extJwt.MapClaims{"foo": "bar"}
+}
+
+func (s *JWKSTestSuite) SetupTest() {
+	s.server = newJWKSServer(
+		newJWK("rsa-1", &s.rsaKey.PublicKey),
+		newJWK("ec-1", &s.ecKey.PublicKey),
+		map[string]any{"kid": "broken", "kty": "RSA", "n": "!", "e": "AQAB"},
+		map[string]any{"kid": "unknown", "kty": "foo"},
+	)
+}
+
+func (s *JWKSTestSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func TestJWKSTestSuite(t *testing.T) {
+	suite.Run(t, new(JWKSTestSuite))
+}
+
+func (s *JWKSTestSuite) TestNewJWKS_MissingURL() {
+	_, err := jwt.NewJWKS(context.TODO(), jwt.JWKSConfig{})
+	assert.Error(s.T(), err, "there must be an error")
+}
+
+func (s *JWKSTestSuite) TestNewJWKS_InitialFetchFails() {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	_, err := jwt.NewJWKS(context.TODO(), jwt.JWKSConfig{URL: srv.URL})
+	assert.Error(s.T(), err, "there must be an error")
+}
+
+func (s *JWKSTestSuite) TestRSAKey() {
+	jwks, err := jwt.NewJWKS(context.TODO(), jwt.JWKSConfig{URL: s.server.URL})
+	require.NoError(s.T(), err)
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{KeySource: jwks, SigningMethods: []string{extJwt.SigningMethodRS256.Name}})
+
+	token := newSignedTokenWithKid(extJwt.SigningMethodRS256, s.claims, "rsa-1", s.rsaKey)
+	_, err = authFunc(incomingCtxWithToken(context.TODO(), "Bearer", token))
+
+	require.NoError(s.T(), err, "no error must occur")
+}
+
+func (s *JWKSTestSuite) TestECKey() {
+	jwks, err := jwt.NewJWKS(context.TODO(), jwt.JWKSConfig{URL: s.server.URL})
+	require.NoError(s.T(), err)
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{KeySource: jwks, SigningMethods: []string{extJwt.SigningMethodES256.Name}})
+
+	token := newSignedTokenWithKid(extJwt.SigningMethodES256, s.claims, "ec-1", s.ecKey)
+	_, err = authFunc(incomingCtxWithToken(context.TODO(), "Bearer", token))
+
+	require.NoError(s.T(), err, "no error must occur")
+}
+
+func (s *JWKSTestSuite) TestUnknownKid() {
+	jwks, err := jwt.NewJWKS(context.TODO(), jwt.JWKSConfig{URL: s.server.URL})
+	require.NoError(s.T(), err)
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{KeySource: jwks, SigningMethods: []string{extJwt.SigningMethodRS256.Name}})
+
+	token := newSignedTokenWithKid(extJwt.SigningMethodRS256, s.claims, "rsa-2", s.rsaKey)
+	_, err = authFunc(incomingCtxWithToken(context.TODO(), "Bearer", token))
+
+	assert.Error(s.T(), err, "there must be an error")
+	assert.Equal(s.T(), codes.Unauthenticated, status.Code(err), "must error with unauthenticated")
+}
+
+func (s *JWKSTestSuite) TestBackgroundRefresh() {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	jwks, err := jwt.NewJWKS(ctx, jwt.JWKSConfig{URL: s.server.URL, RefreshInterval: 20 * time.Millisecond})
+	require.NoError(s.T(), err)
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{KeySource: jwks, SigningMethods: []string{extJwt.SigningMethodRS256.Name}})
+
+	rotatedKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	s.server.setKeys(newJWK("rsa-2", &rotatedKey.PublicKey))
+	token := newSignedTokenWithKid(extJwt.SigningMethodRS256, s.claims, "rsa-2", rotatedKey)
+
+	assert.Eventually(s.T(), func() bool {
+		_, err := authFunc(incomingCtxWithToken(context.TODO(), "Bearer", token))
+		return err == nil
+	}, time.Second, 10*time.Millisecond, "rotated key must be picked up by the background refresh")
+}
+
+func (s *JWKSTestSuite) TestOnDemandRefresh() {
+	jwks, err := jwt.NewJWKS(context.TODO(), jwt.JWKSConfig{URL: s.server.URL, MinRefreshInterval: time.Nanosecond})
+	require.NoError(s.T(), err)
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{KeySource: jwks, SigningMethods: []string{extJwt.SigningMethodRS256.Name}})
+
+	rotatedKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	s.server.setKeys(newJWK("rsa-2", &rotatedKey.PublicKey))
+	token := newSignedTokenWithKid(extJwt.SigningMethodRS256, s.claims, "rsa-2", rotatedKey)
+	_, err = authFunc(incomingCtxWithToken(context.TODO(), "Bearer", token))
+
+	require.NoError(s.T(), err, "unknown kid must trigger a refetch")
+	assert.Equal(s.T(), jwt.JWKSStats{Hits: 0, Misses: 1, Refreshes: 2}, jwks.Stats())
+}
+
+func (s *JWKSTestSuite) TestOnDemandRefresh_RateLimited() {
+	jwks, err := jwt.NewJWKS(context.TODO(), jwt.JWKSConfig{URL: s.server.URL})
+	require.NoError(s.T(), err)
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{KeySource: jwks, SigningMethods: []string{extJwt.SigningMethodRS256.Name}})
+
+	for _, kid := range []string{"a", "b", "c", "d"} {
+		token := newSignedTokenWithKid(extJwt.SigningMethodRS256, s.claims, kid, s.rsaKey)
+		_, err = authFunc(incomingCtxWithToken(context.TODO(), "Bearer", token))
+		assert.Equal(s.T(), codes.Unauthenticated, status.Code(err), "must error with unauthenticated")
+	}
+	token := newSignedTokenWithKid(extJwt.SigningMethodRS256, s.claims, "rsa-1", s.rsaKey)
+	_, err = authFunc(incomingCtxWithToken(context.TODO(), "Bearer", token))
+	require.NoError(s.T(), err, "no error must occur")
+
+	assert.Equal(s.T(), 1, s.server.requestCount(), "unknown kids must not trigger refetches within the minimum interval")
+	assert.Equal(s.T(), jwt.JWKSStats{Hits: 1, Misses: 4, Refreshes: 1}, jwks.Stats())
+}
+
+func (s *JWKSTestSuite) TestOnDemandRefresh_ConcurrentMisses() {
+	jwks, err := jwt.NewJWKS(context.TODO(), jwt.JWKSConfig{URL: s.server.URL, MinRefreshInterval: time.Nanosecond})
+	require.NoError(s.T(), err)
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{KeySource: jwks, SigningMethods: []string{extJwt.SigningMethodRS256.Name}})
+
+	rotatedKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	s.server.setKeys(newJWK("rsa-2", &rotatedKey.PublicKey))
+	s.server.setDelay(100 * time.Millisecond)
+	token := newSignedTokenWithKid(extJwt.SigningMethodRS256, s.claims, "rsa-2", rotatedKey)
+
+	var wg sync.WaitGroup
+	for range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := authFunc(incomingCtxWithToken(context.TODO(), "Bearer", token))
+			assert.NoError(s.T(), err, "no error must occur")
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(s.T(), 2, s.server.requestCount(), "concurrent misses must share a single refetch")
+}
@@ -2,6 +2,14 @@ package jwt
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
@@ -11,6 +19,24 @@ import (
 
 type ContextKey string
 
+// KeySource supplies token validation keys, e.g. from a remote JSON Web Key Set.
+type KeySource interface {
+	// LookupKey returns the key to validate the given token with.
+	// The token's signing method has already been checked when LookupKey is called.
+	LookupKey(token *jwt.Token) (any, error)
+}
+
+// VerificationKey binds a token validation key to the signing algorithms it may be used with.
+// It can be used as SigningKey, as a value of SigningKeys or be returned by a KeySource.
+type VerificationKey struct {
+	// Key to validate the token with.
+	Key any
+
+	// Algorithms the key may be used with. A token's algorithm must be listed here and in the Config's SigningMethods.
+	// Optional. Defaults to all SigningMethods matching the key type.
+	Algorithms []string
+}
+
 // Config defines the config for JWT middleware.
 type Config struct {
 	// Context key to store user information from the token into context.
@@ -18,31 +44,50 @@ type Config struct {
 	ContextKey ContextKey
 
 	// Signing key to validate token.
-	// This is one of the three options to provide a token validation key.
-	// The order of precedence is a user-defined KeyFunc, SigningKeys and SigningKey.
-	// Claims will be accepted without verification, if neither user-defined KeyFunc nor SigningKey nor SigningKeys is provided.
+	// This is one of the four options to provide a token validation key.
+	// The order of precedence is a user-defined KeyFunc, KeySource, SigningKeys and SigningKey.
+	// Tokens are rejected, if neither user-defined KeyFunc nor KeySource nor SigningKey nor SigningKeys is provided,
+	// unless InsecureSkipSignatureVerification is set.
 	SigningKey any
 
 	// Map of signing keys to validate token with kid field usage.
-	// This is one of the three options to provide a token validation key.
-	// The order of precedence is a user-defined KeyFunc, SigningKeys and SigningKey.
-	// Claims will be accepted without verification, if neither user-defined KeyFunc nor SigningKey nor SigningKeys is provided.
+	// The map must not be changed while the server is running, use a KeySet as KeySource instead.
+	// This is one of the four options to provide a token validation key.
+	// The order of precedence is a user-defined KeyFunc, KeySource, SigningKeys and SigningKey.
+	// Tokens are rejected, if neither user-defined KeyFunc nor KeySource nor SigningKey nor SigningKeys is provided,
+	// unless InsecureSkipSignatureVerification is set.
 	SigningKeys map[string]any
 
+	// KeySource supplies validation keys looked up from the token, e.g. a remote JWKS (see NewJWKS).
+	// This is one of the four options to provide a token validation key.
+	// The order of precedence is a user-defined KeyFunc, KeySource, SigningKeys and SigningKey.
+	// Tokens are rejected, if neither user-defined KeyFunc nor KeySource nor SigningKey nor SigningKeys is provided,
+	// unless InsecureSkipSignatureVerification is set.
+	KeySource KeySource
+
 	// Signing method used to check the token's signing algorithm.
 	// Optional. Default value HS256.
+	//
+	// Deprecated: Use SigningMethods, which takes precedence if set.
 	SigningMethod string
 
+	// Allow-list of signing methods used to check the token's signing algorithm.
+	// A key is only used with algorithms matching its type, e.g. an *rsa.PublicKey is never used for HS256.
+	// Wrap a key in a VerificationKey to restrict it further. The "none" algorithm is always rejected.
+	// Optional. Default value SigningMethod or HS256.
+	SigningMethods []string
+
 	// KeyFunc defines a user-defined function that supplies the public key for a token validation.
 	// The function shall take care of verifying the signing algorithm and selecting the proper key.
 	// A user-defined KeyFunc can be useful if tokens are issued by an external party.
 	// Used by default ParseTokenFunc implementation.
 	//
-	// When a user-defined KeyFunc is provided, SigningKey, SigningKeys, and SigningMethod are ignored.
-	// This is one of the three options to provide a token validation key.
-	// The order of precedence is a user-defined KeyFunc, SigningKeys and SigningKey.
-	// Claims will be accepted without verification, if neither user-defined KeyFunc nor SigningKey nor SigningKeys is provided.
-	// Not used if custom ParseTokenFunc is set or neither user-defined KeyFunc nor SigningKey nor SigningKeys is provided.
+	// When a user-defined KeyFunc is provided, KeySource, SigningKey, SigningKeys, SigningMethod and SigningMethods are ignored.
+	// This is one of the four options to provide a token validation key.
+	// The order of precedence is a user-defined KeyFunc, KeySource, SigningKeys and SigningKey.
+	// Tokens are rejected, if neither user-defined KeyFunc nor KeySource nor SigningKey nor SigningKeys is provided,
+	// unless InsecureSkipSignatureVerification is set.
+	// Not used if custom ParseTokenFunc is set.
 	// Default to an internal implementation verifying the signing algorithm and selecting the proper key.
 	KeyFunc jwt.Keyfunc
 
@@ -59,6 +104,85 @@ type Config struct {
 	// Not used if custom ParseTokenFunc is set.
 	// Optional. Defaults to function returning jwt.MapClaims
 	NewClaimsFunc func(c context.Context) jwt.Claims
+
+	// Issuers accepted in the token's iss claim. Used by default ParseTokenFunc implementation.
+	// Optional. The issuer is not checked if empty.
+	Issuers []string
+
+	// Audiences of which at least one must be listed in the token's aud claim. Used by default ParseTokenFunc
+	// implementation.
+	// Optional. The audience is not checked if empty.
+	Audiences []string
+
+	// Leeway to account for clock skew when validating the exp, nbf and iat claims. Used by default ParseTokenFunc
+	// implementation.
+	// Optional. Default value 0.
+	Leeway time.Duration
+
+	// RequireExpiration rejects tokens without exp claim. Used by default ParseTokenFunc implementation.
+	// Optional. Default value false.
+	RequireExpiration bool
+
+	// RequireIssuedAt rejects tokens without iat claim and tokens issued in the future. Used by default
+	// ParseTokenFunc implementation.
+	// Optional. Default value false.
+	RequireIssuedAt bool
+
+	// MaxTokenLifetime rejects tokens whose exp claim is further than MaxTokenLifetime after their iat claim.
+	// Both claims are required if set. Used by default ParseTokenFunc implementation.
+	// Optional. The lifetime is not checked if zero.
+	MaxTokenLifetime time.Duration
+
+	// InsecureSkipSignatureVerification accepts tokens WITHOUT VERIFYING THEIR SIGNATURE if neither user-defined
+	// KeyFunc nor KeySource nor SigningKey nor SigningKeys is provided. Anyone can forge such tokens, so this must
+	// only be used if the token has already been verified upstream, e.g. by a trusted proxy.
+	// Optional. Default value false.
+	InsecureSkipSignatureVerification bool
+
+	// RevocationStore is consulted after the token's signature and claims have been verified and rejects revoked
+	// tokens, ahead of Validators. Also consulted for a custom ParseTokenFunc, which must then return a *jwt.Token.
+	// With Leeway, revocations must outlive the revoked tokens' exp by Leeway, see
+	// NewMemoryRevocationStoreWithLeeway.
+	// Optional.
+	RevocationStore RevocationStore
+
+	// ReplayStore turns on replay protection for one-time-use tokens. The jti of each accepted token is recorded
+	// until its exp plus Leeway, and tokens presented a second time are rejected. Tokens without jti or exp claim
+	// are rejected. Checked last, after Validators and, for calls authenticated by a Policy, after the method's
+	// requirements, so that rejected calls don't use up the token. Also checked for a custom ParseTokenFunc, which
+	// must then return a *jwt.Token.
+	// Optional.
+	ReplayStore ReplayStore
+
+	// RequireCertificateBinding rejects tokens not bound to the client certificate of the call's mutual TLS
+	// connection, i.e. tokens whose cnf.x5t#S256 claim is missing or doesn't match the SHA-256 thumbprint of the
+	// client certificate (RFC 8705). Checked ahead of RevocationStore, also for a custom ParseTokenFunc, which must
+	// then return a *jwt.Token.
+	// Optional. Default value false.
+	RequireCertificateBinding bool
+
+	// DPoP turns on sender-constrained tokens with DPoP proofs (RFC 9449). Calls may then use the "DPoP" auth scheme
+	// in addition to AuthScheme. Checked ahead of RevocationStore and Validators.
+	// Optional.
+	DPoP *DPoPConfig
+
+	// ValidateClaims hooks run in order on the token returned by ParseTokenFunc, after the checks configured above,
+	// to check the claims against the call, e.g. a tenant header against the tenant_id claim. An error that is not
+	// a gRPC status rejects the call with codes.PermissionDenied. Also run for a custom ParseTokenFunc, which must
+	// then return a *jwt.Token.
+	// Optional.
+	ValidateClaims []ClaimsValidatorFunc
+
+	// Validators run in order on the token returned by ParseTokenFunc, after the checks configured above.
+	// The first failing validator rejects the call with its status, e.g. an IntrospectionValidator escalating
+	// high-risk calls to the authorization server. Also run for a custom ParseTokenFunc, which must then return
+	// a *jwt.Token.
+	// Optional.
+	Validators []Validator
+
+	parser          *jwt.Parser
+	validators      []Validator
+	replayValidator Validator
 }
 
 const (
@@ -71,10 +195,19 @@ func NewAuthFunc(signingKey any) auth.AuthFunc {
 	return NewAuthFuncWithConfig(Config{SigningKey: signingKey})
 }
 
+// NewAuthFuncWithValidatedConfig validates the config and returns an auth.AuthFunc for it.
+// Unlike NewAuthFuncWithConfig, an incomplete or inconsistent config is reported as error. See Config.Validate.
+func NewAuthFuncWithValidatedConfig(config Config) (auth.AuthFunc, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return NewAuthFuncWithConfig(config), nil
+}
+
 func NewAuthFuncWithConfig(config Config) auth.AuthFunc {
 	config.setDefaults()
 	return func(c context.Context) (context.Context, error) {
-		auth, err := auth.AuthFromMD(c, config.AuthScheme)
+		auth, dpop, err := config.authFromMD(c)
 		if err != nil {
 			return nil, err
 		}
@@ -82,7 +215,17 @@ func NewAuthFuncWithConfig(config Config) auth.AuthFunc {
 		if err != nil {
 			return nil, err
 		}
+		if err := config.checkDPoP(c, auth, token, dpop); err != nil {
+			return nil, err
+		}
+		if err := config.runValidators(c, token); err != nil {
+			return nil, err
+		}
+		if err := config.checkReplay(c, token); err != nil {
+			return nil, err
+		}
 		newCtx := context.WithValue(c, config.ContextKey, token)
+		newCtx = newContextWithAuthInfo(newCtx, token)
 		return newCtx, nil
 	}
 }
@@ -94,8 +237,12 @@ func (config *Config) setDefaults() {
 	if config.AuthScheme == "" {
 		config.AuthScheme = "Bearer"
 	}
-	if config.SigningMethod == "" {
-		config.SigningMethod = AlgorithmHS256
+	if len(config.SigningMethods) == 0 {
+		if config.SigningMethod == "" {
+			config.SigningMethods = []string{AlgorithmHS256}
+		} else {
+			config.SigningMethods = []string{config.SigningMethod}
+		}
 	}
 	if config.NewClaimsFunc == nil {
 		config.NewClaimsFunc = func(c context.Context) jwt.Claims {
@@ -103,21 +250,67 @@ func (config *Config) setDefaults() {
 		}
 	}
 	if config.ParseTokenFunc == nil {
-		if config.SigningKey == nil && len(config.SigningKeys) == 0 && config.KeyFunc == nil {
-			config.ParseTokenFunc = config.defaultParseTokenFuncWithoutVerify
-		} else {
+		switch {
+		case config.hasKeyMaterial():
 			config.ParseTokenFunc = config.defaultParseTokenFunc
+		case config.InsecureSkipSignatureVerification:
+			config.ParseTokenFunc = config.defaultParseTokenFuncWithoutVerify
+		default:
+			config.ParseTokenFunc = rejectingParseTokenFunc
 		}
 	}
+	config.validators = config.defaultValidators()
+	if config.ReplayStore != nil {
+		config.replayValidator = ReplayValidator(config.ReplayStore, config.Leeway)
+	}
+	for _, validateClaims := range config.ValidateClaims {
+		config.validators = append(config.validators, validateClaims)
+	}
+	config.validators = append(config.validators, config.Validators...)
 	if config.KeyFunc == nil {
 		config.KeyFunc = config.defaultKeyFunc
 	}
+	if config.parser == nil {
+		config.parser = jwt.NewParser(config.parserOptions()...)
+	}
+	if config.DPoP != nil {
+		dpop := *config.DPoP
+		dpop.setDefaults()
+		config.DPoP = &dpop
+	}
+}
+
+func (config *Config) hasKeyMaterial() bool {
+	return config.SigningKey != nil || len(config.SigningKeys) > 0 || config.KeySource != nil || config.KeyFunc != nil
+}
+
+func (config *Config) parserOptions() []jwt.ParserOption {
+	opts := []jwt.ParserOption{jwt.WithLeeway(config.Leeway)}
+	if config.RequireExpiration || config.MaxTokenLifetime > 0 {
+		opts = append(opts, jwt.WithExpirationRequired())
+	}
+	if config.RequireIssuedAt || config.MaxTokenLifetime > 0 {
+		opts = append(opts, jwt.WithIssuedAt())
+	}
+	return opts
 }
 
 func (config *Config) defaultKeyFunc(token *jwt.Token) (any, error) {
-	if token.Method.Alg() != config.SigningMethod {
+	alg := token.Method.Alg()
+	if alg == jwt.SigningMethodNone.Alg() || !slices.Contains(config.SigningMethods, alg) {
 		return nil, status.Errorf(codes.Unauthenticated, "unexpected jwt signing method=%v", token.Header["alg"])
 	}
+	key, err := config.lookupKey(token)
+	if err != nil {
+		return nil, err
+	}
+	return verificationKeyFor(alg, key)
+}
+
+func (config *Config) lookupKey(token *jwt.Token) (any, error) {
+	if config.KeySource != nil {
+		return config.KeySource.LookupKey(token)
+	}
 	if len(config.SigningKeys) == 0 {
 		return config.SigningKey, nil
 	}
@@ -129,17 +322,143 @@ func (config *Config) defaultKeyFunc(token *jwt.Token) (any, error) {
 	return nil, status.Errorf(codes.Unauthenticated, "unexpected jwt key id=%v", token.Header["kid"])
 }
 
+// verificationKeyFor unwraps a VerificationKey and makes sure the key may be used with the given algorithm.
+func verificationKeyFor(alg string, key any) (any, error) {
+	vk := asVerificationKey(key)
+	if len(vk.Algorithms) > 0 && !slices.Contains(vk.Algorithms, alg) {
+		return nil, status.Errorf(codes.Unauthenticated, "unexpected jwt signing method=%v for key", alg)
+	}
+	if err := checkKeyType(alg, vk.Key); err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "%v", err)
+	}
+	return vk.Key, nil
+}
+
+func asVerificationKey(key any) VerificationKey {
+	switch k := key.(type) {
+	case VerificationKey:
+		return k
+	case *VerificationKey:
+		if k != nil {
+			return *k
+		}
+	}
+	return VerificationKey{Key: key}
+}
+
+// checkKeyType makes sure the key type belongs to the algorithm family, which prevents e.g. an RSA public key
+// from being used as HMAC secret. Keys of signing methods registered by the user are passed through unchecked.
+func checkKeyType(alg string, key any) error {
+	switch {
+	case strings.HasPrefix(alg, "HS"):
+		if _, ok := key.([]byte); !ok {
+			return fmt.Errorf("unexpected key type=%T for jwt signing method=%v", key, alg)
+		}
+	case strings.HasPrefix(alg, "RS"), strings.HasPrefix(alg, "PS"):
+		if _, ok := key.(*rsa.PublicKey); !ok {
+			return fmt.Errorf("unexpected key type=%T for jwt signing method=%v", key, alg)
+		}
+	case strings.HasPrefix(alg, "ES"):
+		k, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("unexpected key type=%T for jwt signing method=%v", key, alg)
+		}
+		if method, ok := jwt.GetSigningMethod(alg).(*jwt.SigningMethodECDSA); ok && k.Curve.Params().BitSize != method.CurveBits {
+			return fmt.Errorf("unexpected key curve=%v for jwt signing method=%v", k.Curve.Params().Name, alg)
+		}
+	case alg == jwt.SigningMethodEdDSA.Alg():
+		k, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return fmt.Errorf("unexpected key type=%T for jwt signing method=%v", key, alg)
+		}
+		if len(k) != ed25519.PublicKeySize {
+			return fmt.Errorf("unexpected key size=%v for jwt signing method=%v", len(k), alg)
+		}
+	}
+	return nil
+}
+
 func (config *Config) defaultParseTokenFunc(c context.Context, auth string) (any, error) {
-	token, err := jwt.ParseWithClaims(auth, config.NewClaimsFunc(c), config.KeyFunc)
+	token, err := config.parser.ParseWithClaims(auth, config.NewClaimsFunc(c), config.KeyFunc)
 	if err != nil {
-		return nil, status.Errorf(codes.Unauthenticated, "invalid token: %v", err)
+		return nil, tokenError(err)
 	}
 	if !token.Valid {
 		return nil, status.Errorf(codes.Unauthenticated, "invalid token")
 	}
+	if err := config.validateRegisteredClaims(token.Claims); err != nil {
+		return nil, err
+	}
 	return token, nil
 }
 
+// validateRegisteredClaims performs the registered claim checks not covered by the jwt.Parser.
+func (config *Config) validateRegisteredClaims(claims jwt.Claims) error {
+	if len(config.Issuers) > 0 {
+		iss, _ := claims.GetIssuer()
+		if !slices.Contains(config.Issuers, iss) {
+			return status.Errorf(codes.Unauthenticated, "invalid token: unexpected issuer=%q", iss)
+		}
+	}
+	if len(config.Audiences) > 0 {
+		aud, _ := claims.GetAudience()
+		if len(aud) == 0 {
+			return status.Error(codes.Unauthenticated, "invalid token: missing required claim aud")
+		}
+		if !slices.ContainsFunc(aud, func(a string) bool { return slices.Contains(config.Audiences, a) }) {
+			return status.Errorf(codes.Unauthenticated, "invalid token: unexpected audience=%q", aud)
+		}
+	}
+	if !config.RequireIssuedAt && config.MaxTokenLifetime <= 0 {
+		return nil
+	}
+	iat, err := claims.GetIssuedAt()
+	if err != nil || iat == nil {
+		return status.Error(codes.Unauthenticated, "invalid token: missing required claim iat")
+	}
+	if config.MaxTokenLifetime > 0 {
+		exp, err := claims.GetExpirationTime()
+		if err != nil || exp == nil {
+			return status.Error(codes.Unauthenticated, "invalid token: missing required claim exp")
+		}
+		if exp.Sub(iat.Time) > config.MaxTokenLifetime {
+			return status.Errorf(codes.Unauthenticated, "invalid token: lifetime exceeds %v", config.MaxTokenLifetime)
+		}
+	}
+	return nil
+}
+
+// tokenError maps errors of the jwt.Parser to Unauthenticated statuses describing the failed check.
+func tokenError(err error) error {
+	var msg string
+	switch {
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		msg = "invalid token: malformed"
+	case errors.Is(err, jwt.ErrTokenUnverifiable), errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		var keyErr interface{ GRPCStatus() *status.Status }
+		if errors.As(err, &keyErr) {
+			return status.Errorf(codes.Unauthenticated, "invalid token: %v", keyErr.GRPCStatus().Message())
+		}
+		msg = "invalid token: signature is invalid"
+	case errors.Is(err, jwt.ErrTokenRequiredClaimMissing):
+		msg = "invalid token: missing required claim exp"
+	case errors.Is(err, jwt.ErrTokenExpired):
+		msg = "invalid token: expired"
+	case errors.Is(err, jwt.ErrTokenNotValidYet):
+		msg = "invalid token: not valid yet"
+	case errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		msg = "invalid token: used before issued"
+	default:
+		msg = fmt.Sprintf("invalid token: %v", err)
+	}
+	return status.Error(codes.Unauthenticated, msg)
+}
+
+// rejectingParseTokenFunc fails closed if no token validation key is configured.
+func rejectingParseTokenFunc(c context.Context, auth string) (any, error) {
+	return nil, status.Error(codes.Unauthenticated, "invalid token: no token validation key configured")
+}
+
 func (config *Config) defaultParseTokenFuncWithoutVerify(c context.Context, auth string) (any, error) {
 	token, _, err := jwt.NewParser().ParseUnverified(auth, config.NewClaimsFunc(c))
 	if err != nil {
@@ -0,0 +1,96 @@
+package jwt_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ErenDursun/go-grpc-jwt-middleware/jwt"
+	extJwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type RegisteredClaimsTestSuite struct {
+	suite.Suite
+
+	secret []byte
+}
+
+func (s *RegisteredClaimsTestSuite) SetupSuite() {
+	s.secret = []byte("good_secret")
+}
+
+func TestRegisteredClaimsTestSuite(t *testing.T) {
+	suite.Run(t, new(RegisteredClaimsTestSuite))
+}
+
+func (s *RegisteredClaimsTestSuite) auth(config jwt.Config, claims extJwt.MapClaims) error {
+	config.SigningKey = s.secret
+	authFunc := jwt.NewAuthFuncWithConfig(config)
+	_, err := authFunc(incomingCtxWithToken(context.TODO(), "Bearer", newSignedToken(extJwt.SigningMethodHS256, claims, s.secret)))
+	return err
+}
+
+func (s *RegisteredClaimsTestSuite) assertUnauthenticated(err error, msg string) {
+	assert.Equal(s.T(), codes.Unauthenticated, status.Code(err), "must error with unauthenticated")
+	assert.Equal(s.T(), msg, status.Convert(err).Message())
+}
+
+func (s *RegisteredClaimsTestSuite) TestIssuer() {
+	config := jwt.Config{Issuers: []string{"https://a.example.com", "https://b.example.com"}}
+
+	require.NoError(s.T(), s.auth(config, extJwt.MapClaims{"iss": "https://b.example.com"}))
+	s.assertUnauthenticated(s.auth(config, extJwt.MapClaims{"iss": "https://c.example.com"}), `invalid token: unexpected issuer="https://c.example.com"`)
+	s.assertUnauthenticated(s.auth(config, extJwt.MapClaims{}), `invalid token: unexpected issuer=""`)
+}
+
+func (s *RegisteredClaimsTestSuite) TestAudience() {
+	config := jwt.Config{Audiences: []string{"svc-a"}}
+
+	require.NoError(s.T(), s.auth(config, extJwt.MapClaims{"aud": []string{"svc-b", "svc-a"}}))
+	require.NoError(s.T(), s.auth(config, extJwt.MapClaims{"aud": "svc-a"}))
+	s.assertUnauthenticated(s.auth(config, extJwt.MapClaims{"aud": "svc-b"}), `invalid token: unexpected audience=["svc-b"]`)
+	s.assertUnauthenticated(s.auth(config, extJwt.MapClaims{}), "invalid token: missing required claim aud")
+}
+
+func (s *RegisteredClaimsTestSuite) TestExpiration() {
+	expired := extJwt.MapClaims{"exp": float64(time.Now().Add(-10 * time.Second).Unix())}
+
+	s.assertUnauthenticated(s.auth(jwt.Config{}, expired), "invalid token: expired")
+	require.NoError(s.T(), s.auth(jwt.Config{Leeway: time.Minute}, expired), "leeway must account for clock skew")
+}
+
+func (s *RegisteredClaimsTestSuite) TestNotBefore() {
+	notYetValid := extJwt.MapClaims{"nbf": float64(time.Now().Add(10 * time.Second).Unix())}
+
+	s.assertUnauthenticated(s.auth(jwt.Config{}, notYetValid), "invalid token: not valid yet")
+	require.NoError(s.T(), s.auth(jwt.Config{Leeway: time.Minute}, notYetValid), "leeway must account for clock skew")
+}
+
+func (s *RegisteredClaimsTestSuite) TestRequireExpiration() {
+	config := jwt.Config{RequireExpiration: true}
+
+	require.NoError(s.T(), s.auth(config, extJwt.MapClaims{"exp": float64(time.Now().Add(time.Minute).Unix())}))
+	s.assertUnauthenticated(s.auth(config, extJwt.MapClaims{}), "invalid token: missing required claim exp")
+}
+
+func (s *RegisteredClaimsTestSuite) TestRequireIssuedAt() {
+	config := jwt.Config{RequireIssuedAt: true}
+
+	require.NoError(s.T(), s.auth(config, extJwt.MapClaims{"iat": float64(time.Now().Unix())}))
+	s.assertUnauthenticated(s.auth(config, extJwt.MapClaims{}), "invalid token: missing required claim iat")
+	s.assertUnauthenticated(s.auth(config, extJwt.MapClaims{"iat": float64(time.Now().Add(time.Hour).Unix())}), "invalid token: used before issued")
+}
+
+func (s *RegisteredClaimsTestSuite) TestMaxTokenLifetime() {
+	config := jwt.Config{MaxTokenLifetime: time.Hour}
+	now := time.Now()
+
+	require.NoError(s.T(), s.auth(config, extJwt.MapClaims{"iat": float64(now.Unix()), "exp": float64(now.Add(time.Hour).Unix())}))
+	s.assertUnauthenticated(s.auth(config, extJwt.MapClaims{"iat": float64(now.Unix()), "exp": float64(now.Add(2 * time.Hour).Unix())}), "invalid token: lifetime exceeds 1h0m0s")
+	s.assertUnauthenticated(s.auth(config, extJwt.MapClaims{"iat": float64(now.Unix())}), "invalid token: missing required claim exp")
+}
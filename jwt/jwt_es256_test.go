@@ -54,8 +54,8 @@ func (suite *ES256TestSuite) SetupSuite() {
 
 	authFunc := jwt.NewAuthFuncWithConfig(
 		jwt.Config{
-			SigningMethod: extJwt.SigningMethodES256.Name,
-			SigningKey:    &goodKey.PublicKey,
+			SigningMethods: []string{extJwt.SigningMethodES256.Name},
+			SigningKey:     &goodKey.PublicKey,
 		},
 	)
 
@@ -0,0 +1,128 @@
+package jwt_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/ErenDursun/go-grpc-jwt-middleware/jwt"
+	extJwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type SigningMethodsTestSuite struct {
+	suite.Suite
+
+	secret []byte
+	rsaKey *rsa.PrivateKey
+	claims extJwt.MapClaims
+}
+
+func (s *SigningMethodsTestSuite) SetupSuite() {
+	s.secret = []byte("good_secret")
+	s.rsaKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+	s.claims = extJwt.MapClaims{"foo": "bar"}
+}
+
+func TestSigningMethodsTestSuite(t *testing.T) {
+	suite.Run(t, new(SigningMethodsTestSuite))
+}
+
+func (s *SigningMethodsTestSuite) assertUnauthenticated(authFunc func(context.Context) (context.Context, error), token string) {
+	_, err := authFunc(incomingCtxWithToken(context.TODO(), "Bearer", token))
+	assert.Error(s.T(), err, "there must be an error")
+	assert.Equal(s.T(), codes.Unauthenticated, status.Code(err), "must error with unauthenticated")
+}
+
+func (s *SigningMethodsTestSuite) TestMultipleSigningMethods() {
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{
+		SigningKeys:    map[string]any{"hs": s.secret, "rs": &s.rsaKey.PublicKey},
+		SigningMethods: []string{extJwt.SigningMethodHS256.Name, extJwt.SigningMethodRS256.Name},
+	})
+
+	for _, token := range []string{
+		newSignedTokenWithKid(extJwt.SigningMethodHS256, s.claims, "hs", s.secret),
+		newSignedTokenWithKid(extJwt.SigningMethodRS256, s.claims, "rs", s.rsaKey),
+	} {
+		_, err := authFunc(incomingCtxWithToken(context.TODO(), "Bearer", token))
+		require.NoError(s.T(), err, "no error must occur")
+	}
+}
+
+func (s *SigningMethodsTestSuite) TestSigningMethodNotAllowed() {
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{
+		SigningKey:     &s.rsaKey.PublicKey,
+		SigningMethods: []string{extJwt.SigningMethodRS256.Name},
+	})
+
+	s.assertUnauthenticated(authFunc, newSignedToken(extJwt.SigningMethodRS512, s.claims, s.rsaKey))
+}
+
+func (s *SigningMethodsTestSuite) TestKeyAlgorithmConfusion() {
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{
+		SigningKeys:    map[string]any{"rs": &s.rsaKey.PublicKey},
+		SigningMethods: []string{extJwt.SigningMethodHS256.Name, extJwt.SigningMethodRS256.Name},
+	})
+	der, _ := x509.MarshalPKIXPublicKey(&s.rsaKey.PublicKey)
+	publicKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	s.assertUnauthenticated(authFunc, newSignedTokenWithKid(extJwt.SigningMethodHS256, s.claims, "rs", publicKeyPEM))
+}
+
+func (s *SigningMethodsTestSuite) TestKeyTypeMismatch() {
+	edPublicKey, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{
+		SigningKeys:    map[string]any{"rs": &s.rsaKey.PublicKey, "ed": edPublicKey},
+		SigningMethods: []string{extJwt.SigningMethodPS256.Name, extJwt.SigningMethodEdDSA.Alg()},
+	})
+
+	for _, token := range []string{
+		newSignedTokenWithKid(extJwt.SigningMethodPS256, s.claims, "rs", s.rsaKey),
+		newSignedTokenWithKid(extJwt.SigningMethodEdDSA, s.claims, "ed", edKey),
+	} {
+		_, err := authFunc(incomingCtxWithToken(context.TODO(), "Bearer", token))
+		require.NoError(s.T(), err, "no error must occur")
+	}
+	s.assertUnauthenticated(authFunc, newSignedTokenWithKid(extJwt.SigningMethodEdDSA, s.claims, "rs", edKey))
+	s.assertUnauthenticated(authFunc, newSignedTokenWithKid(extJwt.SigningMethodPS256, s.claims, "ed", s.rsaKey))
+}
+
+func (s *SigningMethodsTestSuite) TestNoneAlwaysRejected() {
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{
+		SigningKey:     s.secret,
+		SigningMethods: []string{extJwt.SigningMethodHS256.Name, extJwt.SigningMethodNone.Alg()},
+	})
+
+	s.assertUnauthenticated(authFunc, newSignedToken(extJwt.SigningMethodNone, s.claims, extJwt.UnsafeAllowNoneSignatureType))
+}
+
+func (s *SigningMethodsTestSuite) TestVerificationKeyAlgorithms() {
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{
+		SigningKeys: map[string]any{
+			"rs": jwt.VerificationKey{Key: &s.rsaKey.PublicKey, Algorithms: []string{extJwt.SigningMethodRS256.Name}},
+		},
+		SigningMethods: []string{extJwt.SigningMethodRS256.Name, extJwt.SigningMethodRS512.Name},
+	})
+
+	_, err := authFunc(incomingCtxWithToken(context.TODO(), "Bearer", newSignedTokenWithKid(extJwt.SigningMethodRS256, s.claims, "rs", s.rsaKey)))
+	require.NoError(s.T(), err, "no error must occur")
+	s.assertUnauthenticated(authFunc, newSignedTokenWithKid(extJwt.SigningMethodRS512, s.claims, "rs", s.rsaKey))
+}
+
+func (s *SigningMethodsTestSuite) TestDeprecatedSigningMethod() {
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{
+		SigningKey:    &s.rsaKey.PublicKey,
+		SigningMethod: extJwt.SigningMethodRS256.Name,
+	})
+
+	_, err := authFunc(incomingCtxWithToken(context.TODO(), "Bearer", newSignedToken(extJwt.SigningMethodRS256, s.claims, s.rsaKey)))
+	require.NoError(s.T(), err, "no error must occur")
+}
@@ -2,13 +2,20 @@ package jwt_test
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"crypto/ed25519"
 	"crypto/rand"
+	"crypto/rsa"
 	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
 	"encoding/pem"
 	"fmt"
 	"log"
 	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -51,6 +58,87 @@ func ctxWithToken(ctx context.Context, scheme string, token string) context.Cont
 	return nCtx
 }
 
+// incomingCtxWithToken builds a server side context as seen by an auth.AuthFunc.
+func incomingCtxWithToken(ctx context.Context, scheme string, token string) context.Context {
+	grpcMD := metadata.Pairs("authorization", fmt.Sprintf("%s %v", scheme, token))
+	return metadata.NewIncomingContext(ctx, grpcMD)
+}
+
+// newSignedTokenWithKid signs a token carrying the given kid header.
+func newSignedTokenWithKid(method jwt.SigningMethod, claims jwt.Claims, kid string, secret any) string {
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	signedToken, _ := token.SignedString(secret)
+	return signedToken
+}
+
+// newJWK returns the JWK representation of a public key for the purpose of JWKS tests.
+func newJWK(kid string, key any) map[string]any {
+	b64 := base64.RawURLEncoding.EncodeToString
+	jwk := map[string]any{"kid": kid}
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		jwk["kty"] = "RSA"
+		jwk["n"] = b64(k.N.Bytes())
+		jwk["e"] = b64(big.NewInt(int64(k.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		jwk["kty"] = "EC"
+		jwk["crv"] = k.Curve.Params().Name
+		jwk["x"] = b64(k.X.FillBytes(make([]byte, size)))
+		jwk["y"] = b64(k.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk["kty"] = "OKP"
+		jwk["crv"] = "Ed25519"
+		jwk["x"] = b64(k)
+	case []byte:
+		jwk["kty"] = "oct"
+		jwk["k"] = b64(k)
+	}
+	return jwk
+}
+
+// jwksServer is an httptest server standing in for an identity provider's JWKS endpoint.
+type jwksServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	keys     []map[string]any
+	requests int
+	delay    time.Duration
+}
+
+func newJWKSServer(keys ...map[string]any) *jwksServer {
+	s := &jwksServer{keys: keys}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests++
+		time.Sleep(s.delay)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": s.keys})
+	}))
+	return s
+}
+
+func (s *jwksServer) setKeys(keys ...map[string]any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+}
+
+func (s *jwksServer) setDelay(delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delay = delay
+}
+
+func (s *jwksServer) requestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
 // fakeOAuth2TokenSource implements a fake oauth2.TokenSource for the purpose of credentials test.
 type fakeOAuth2TokenSource struct {
 	accessToken string
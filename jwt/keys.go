@@ -0,0 +1,213 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// LoadKeysFromFile reads verification keys from the file at path. See LoadKeys for the supported formats.
+func LoadKeysFromFile(path string) (map[string]any, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	keys, err := LoadKeys(f)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	return keys, nil
+}
+
+// LoadKeys reads verification keys into a map usable as Config.SigningKeys. The format is detected from the content:
+//   - a JWKS document, whose keys are indexed by kid,
+//   - a single JWK, indexed by its kid,
+//   - PEM blocks holding PKIX public keys ("PUBLIC KEY"), PKCS#1 RSA public keys ("RSA PUBLIC KEY") or
+//     X.509 certificates ("CERTIFICATE"),
+//   - a DER encoded PKIX public key, PKCS#1 RSA public key or X.509 certificate.
+//
+// Keys without kid are indexed by their RFC 7638 thumbprint, see Thumbprint. JWKs carrying an alg parameter are
+// restricted to that algorithm, and JWKs meant for encryption are skipped.
+func LoadKeys(r io.Reader) (map[string]any, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxResponseSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxResponseSize {
+		return nil, fmt.Errorf("key data exceeds %v bytes", maxResponseSize)
+	}
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case len(trimmed) == 0:
+		return nil, errors.New("no keys found")
+	case trimmed[0] == '{':
+		return loadJSONWebKeys(trimmed)
+	case bytes.HasPrefix(trimmed, []byte("-----BEGIN")):
+		return loadPEMKeys(trimmed)
+	default:
+		key, err := parseDERKey(data)
+		if err != nil {
+			return nil, err
+		}
+		return keysByThumbprint(key)
+	}
+}
+
+// loadJSONWebKeys reads a JWKS document or a single JWK.
+func loadJSONWebKeys(data []byte) (map[string]any, error) {
+	var set jsonWebKeySet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid jwk document: %w", err)
+	}
+	if set.Keys == nil {
+		set.Keys = []json.RawMessage{data}
+	}
+	keys := make(map[string]any, len(set.Keys))
+	for _, raw := range set.Keys {
+		var jwk jsonWebKey
+		if err := json.Unmarshal(raw, &jwk); err != nil {
+			return nil, fmt.Errorf("invalid jwk: %w", err)
+		}
+		if jwk.Use == "enc" {
+			continue
+		}
+		key, err := jwk.verificationKey()
+		if err != nil {
+			return nil, err
+		}
+		kid := jwk.Kid
+		if kid == "" {
+			if kid, err = Thumbprint(key); err != nil {
+				return nil, err
+			}
+		}
+		keys[kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no keys found")
+	}
+	return keys, nil
+}
+
+// loadPEMKeys reads all PEM blocks of data.
+func loadPEMKeys(data []byte) (map[string]any, error) {
+	var keys []any
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		var key any
+		var err error
+		switch block.Type {
+		case "PUBLIC KEY":
+			key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		case "CERTIFICATE":
+			var cert *x509.Certificate
+			if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+				key = cert.PublicKey
+			}
+		default:
+			return nil, fmt.Errorf("unsupported pem block type=%v", block.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid pem block type=%v: %w", block.Type, err)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no keys found")
+	}
+	return keysByThumbprint(keys...)
+}
+
+func parseDERKey(der []byte) (any, error) {
+	if key, err := x509.ParsePKIXPublicKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PublicKey(der); err == nil {
+		return key, nil
+	}
+	if cert, err := x509.ParseCertificate(der); err == nil {
+		return cert.PublicKey, nil
+	}
+	return nil, errors.New("unsupported key format")
+}
+
+func keysByThumbprint(keys ...any) (map[string]any, error) {
+	m := make(map[string]any, len(keys))
+	for _, key := range keys {
+		thumbprint, err := Thumbprint(key)
+		if err != nil {
+			return nil, err
+		}
+		m[thumbprint] = key
+	}
+	return m, nil
+}
+
+// Thumbprint returns the base64url encoded SHA-256 JWK thumbprint of the key as defined in RFC 7638.
+// Supported keys are *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey and []byte HMAC secrets, optionally
+// wrapped in a VerificationKey.
+func Thumbprint(key any) (string, error) {
+	// json.Marshal sorts map keys, which yields the lexicographic member order required by RFC 7638.
+	var members map[string]string
+	switch k := asVerificationKey(key).Key.(type) {
+	case *rsa.PublicKey:
+		members = map[string]string{
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(bigEndianBytes(uint64(k.E))),
+		}
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		members = map[string]string{
+			"kty": "EC",
+			"crv": k.Curve.Params().Name,
+			"x":   base64.RawURLEncoding.EncodeToString(k.X.FillBytes(make([]byte, size))),
+			"y":   base64.RawURLEncoding.EncodeToString(k.Y.FillBytes(make([]byte, size))),
+		}
+	case ed25519.PublicKey:
+		members = map[string]string{
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"x":   base64.RawURLEncoding.EncodeToString(k),
+		}
+	case []byte:
+		members = map[string]string{
+			"kty": "oct",
+			"k":   base64.RawURLEncoding.EncodeToString(k),
+		}
+	default:
+		return "", fmt.Errorf("unsupported key type=%T", k)
+	}
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// bigEndianBytes returns the minimal big-endian representation of v.
+func bigEndianBytes(v uint64) []byte {
+	var b []byte
+	for ; v > 0; v >>= 8 {
+		b = append([]byte{byte(v)}, b...)
+	}
+	return b
+}
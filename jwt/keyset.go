@@ -0,0 +1,120 @@
+package jwt
+
+import (
+	"maps"
+	"sync"
+	"sync/atomic"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// KeySet is a KeySource holding verification keys by kid, which can be changed while the server is running.
+// Lookups read an immutable snapshot without locking; changes copy the snapshot and swap it atomically.
+// Keys are wrapped in a VerificationKey to restrict their algorithms, like the values of Config.SigningKeys.
+// The zero value is an empty KeySet ready to use.
+type KeySet struct {
+	mu       sync.Mutex
+	snapshot atomic.Pointer[keySetSnapshot]
+}
+
+// keySetSnapshot is an immutable version of a KeySet's keys.
+type keySetSnapshot struct {
+	keys       map[string]any
+	generation uint64
+}
+
+// NewKeySet returns a KeySet holding a copy of keys.
+func NewKeySet(keys map[string]any) *KeySet {
+	keySet := &KeySet{}
+	keySet.Replace(keys)
+	return keySet
+}
+
+// Add adds the key or replaces the key with the same kid.
+func (keySet *KeySet) Add(kid string, key any) {
+	keySet.update(func(keys map[string]any) map[string]any {
+		keys = maps.Clone(keys)
+		if keys == nil {
+			keys = make(map[string]any, 1)
+		}
+		keys[kid] = key
+		return keys
+	})
+}
+
+// Remove removes the key with the given kid.
+func (keySet *KeySet) Remove(kid string) {
+	keySet.update(func(keys map[string]any) map[string]any {
+		keys = maps.Clone(keys)
+		delete(keys, kid)
+		return keys
+	})
+}
+
+// Replace replaces all keys with a copy of keys.
+func (keySet *KeySet) Replace(keys map[string]any) {
+	keySet.update(func(map[string]any) map[string]any {
+		return maps.Clone(keys)
+	})
+}
+
+// Snapshot returns a copy of the current keys.
+func (keySet *KeySet) Snapshot() map[string]any {
+	keys := maps.Clone(keySet.load().keys)
+	if keys == nil {
+		keys = make(map[string]any)
+	}
+	return keys
+}
+
+// Len returns the number of keys.
+func (keySet *KeySet) Len() int {
+	return len(keySet.load().keys)
+}
+
+// LookupKey returns the key matching the token's kid header. Tokens without kid are validated with the only key,
+// if the KeySet holds exactly one key.
+func (keySet *KeySet) LookupKey(token *jwt.Token) (any, error) {
+	keys := keySet.load().keys
+	kid, ok := token.Header["kid"].(string)
+	if !ok && len(keys) == 1 {
+		for _, key := range keys {
+			return key, nil
+		}
+	}
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, status.Errorf(codes.Unauthenticated, "unexpected jwt key id=%v", token.Header["kid"])
+}
+
+// key returns the key for kid together with the generation of the snapshot it was found in.
+func (keySet *KeySet) key(kid string) (any, bool, uint64) {
+	snapshot := keySet.load()
+	key, ok := snapshot.keys[kid]
+	return key, ok, snapshot.generation
+}
+
+// generation returns the number of changes made to the KeySet.
+func (keySet *KeySet) generation() uint64 {
+	return keySet.load().generation
+}
+
+var emptyKeySetSnapshot = &keySetSnapshot{}
+
+func (keySet *KeySet) load() *keySetSnapshot {
+	if snapshot := keySet.snapshot.Load(); snapshot != nil {
+		return snapshot
+	}
+	return emptyKeySetSnapshot
+}
+
+// update swaps the snapshot for one with the keys returned by change, which must not modify its argument.
+func (keySet *KeySet) update(change func(keys map[string]any) map[string]any) {
+	keySet.mu.Lock()
+	defer keySet.mu.Unlock()
+	current := keySet.load()
+	keySet.snapshot.Store(&keySetSnapshot{keys: change(current.keys), generation: current.generation + 1})
+}
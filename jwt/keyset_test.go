@@ -0,0 +1,106 @@
+package jwt_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/ErenDursun/go-grpc-jwt-middleware/jwt"
+	extJwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type KeySetTestSuite struct {
+	suite.Suite
+
+	oldSecret []byte
+	newSecret []byte
+	claims    extJwt.MapClaims
+}
+
+func (s *KeySetTestSuite) SetupSuite() {
+	s.oldSecret = []byte("old_secret")
+	s.newSecret = []byte("new_secret")
+	s.claims = extJwt.MapClaims{"foo": "bar"}
+}
+
+func TestKeySetTestSuite(t *testing.T) {
+	suite.Run(t, new(KeySetTestSuite))
+}
+
+func (s *KeySetTestSuite) auth(authFunc func(context.Context) (context.Context, error), kid string, secret []byte) error {
+	_, err := authFunc(incomingCtxWithToken(context.TODO(), "Bearer", newSignedTokenWithKid(extJwt.SigningMethodHS256, s.claims, kid, secret)))
+	return err
+}
+
+func (s *KeySetTestSuite) TestRotation() {
+	keySet := jwt.NewKeySet(map[string]any{"old": s.oldSecret})
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{KeySource: keySet})
+
+	require.NoError(s.T(), s.auth(authFunc, "old", s.oldSecret))
+	require.Error(s.T(), s.auth(authFunc, "new", s.newSecret))
+
+	keySet.Add("new", s.newSecret)
+	require.NoError(s.T(), s.auth(authFunc, "new", s.newSecret), "added key must be used")
+	require.NoError(s.T(), s.auth(authFunc, "old", s.oldSecret))
+
+	keySet.Remove("old")
+	assert.Error(s.T(), s.auth(authFunc, "old", s.oldSecret), "removed key must not be used")
+
+	keySet.Replace(map[string]any{"old": s.oldSecret})
+	require.NoError(s.T(), s.auth(authFunc, "old", s.oldSecret))
+	assert.Error(s.T(), s.auth(authFunc, "new", s.newSecret), "replaced key must not be used")
+}
+
+func (s *KeySetTestSuite) TestSnapshotIsCopy() {
+	keys := map[string]any{"old": s.oldSecret}
+	keySet := jwt.NewKeySet(keys)
+	keys["new"] = s.newSecret
+
+	snapshot := keySet.Snapshot()
+	assert.Equal(s.T(), map[string]any{"old": s.oldSecret}, snapshot, "keys passed to NewKeySet must be copied")
+
+	snapshot["new"] = s.newSecret
+	assert.Equal(s.T(), 1, keySet.Len(), "snapshot changes must not affect the key set")
+}
+
+func (s *KeySetTestSuite) TestZeroValue() {
+	var keySet jwt.KeySet
+	assert.Empty(s.T(), keySet.Snapshot())
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{KeySource: &keySet})
+
+	assert.Error(s.T(), s.auth(authFunc, "old", s.oldSecret))
+
+	keySet.Add("old", s.oldSecret)
+	assert.NoError(s.T(), s.auth(authFunc, "old", s.oldSecret))
+}
+
+func (s *KeySetTestSuite) TestConcurrentUpdates() {
+	keySet := jwt.NewKeySet(map[string]any{"old": s.oldSecret})
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{KeySource: keySet})
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := range 100 {
+				kid := fmt.Sprintf("key-%d-%d", i, j)
+				keySet.Add(kid, s.newSecret)
+				keySet.Remove(kid)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				assert.NoError(s.T(), s.auth(authFunc, "old", s.oldSecret))
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(s.T(), 1, keySet.Len())
+}
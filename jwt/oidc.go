@@ -0,0 +1,195 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// OIDCConfig defines the config for bootstrapping the JWT middleware from an OpenID Connect issuer.
+type OIDCConfig struct {
+	// Issuer URL of the OpenID Connect provider. The discovery document is read from
+	// <Issuer>/.well-known/openid-configuration and its issuer must match exactly.
+	// Required.
+	Issuer string
+
+	// HTTPClient used to download the discovery document and the key set.
+	// Optional. Defaults to a client with a 10 second timeout.
+	HTTPClient *http.Client
+
+	// RefreshInterval defines how often the discovery document is read again in the background.
+	// Optional. Default value 24 hours.
+	RefreshInterval time.Duration
+
+	// JWKSRefreshInterval defines how often the key set is downloaded again in the background.
+	// Optional. Default value 1 hour.
+	JWKSRefreshInterval time.Duration
+
+	// RefreshErrorHandler is called when a background refresh of the discovery document or the key set fails.
+	// The previously downloaded values stay in use.
+	// Optional.
+	RefreshErrorHandler func(err error)
+
+	// Config is the base config of the middleware.
+	// KeyFunc, KeySource, SigningKey, SigningKeys, SigningMethod, SigningMethods and Issuers are ignored and derived
+	// from the discovery document.
+	// Optional.
+	Config Config
+}
+
+const (
+	// DefaultOIDCRefreshInterval is the default interval in which the discovery document is read again.
+	DefaultOIDCRefreshInterval = 24 * time.Hour
+
+	oidcDiscoveryPath = "/.well-known/openid-configuration"
+)
+
+// oidcDiscoveryDocument holds the fields of an OpenID Provider Metadata document used by the middleware.
+type oidcDiscoveryDocument struct {
+	Issuer                           string   `json:"issuer"`
+	JWKSURI                          string   `json:"jwks_uri"`
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// oidcState is the currently active discovery result.
+type oidcState struct {
+	jwksURI    string
+	algorithms []string
+	jwks       *JWKS
+	cancel     context.CancelFunc
+}
+
+type oidcProvider struct {
+	config OIDCConfig
+	state  atomic.Pointer[oidcState]
+}
+
+// NewAuthFuncWithOIDC returns an auth.AuthFunc for tokens issued by an OpenID Connect provider.
+// The discovery document is fetched and validated, and tokens are checked against the key set from its jwks_uri,
+// the algorithms from its id_token_signing_alg_values_supported and its issuer.
+// The discovery document and the key set are refreshed in the background until ctx is done.
+func NewAuthFuncWithOIDC(ctx context.Context, config OIDCConfig) (auth.AuthFunc, error) {
+	if config.Issuer == "" {
+		return nil, errors.New("oidc: missing issuer")
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if config.RefreshInterval <= 0 {
+		config.RefreshInterval = DefaultOIDCRefreshInterval
+	}
+	provider := &oidcProvider{config: config}
+	if err := provider.refresh(ctx); err != nil {
+		return nil, err
+	}
+	go provider.refreshLoop(ctx)
+
+	base := config.Config
+	base.KeyFunc = provider.keyFunc
+	base.Issuers = []string{config.Issuer}
+	return NewAuthFuncWithConfig(base), nil
+}
+
+func (provider *oidcProvider) keyFunc(token *jwt.Token) (any, error) {
+	state := provider.state.Load()
+	alg := token.Method.Alg()
+	if !slices.Contains(state.algorithms, alg) {
+		return nil, status.Errorf(codes.Unauthenticated, "unexpected jwt signing method=%v", token.Header["alg"])
+	}
+	key, err := state.jwks.LookupKey(token)
+	if err != nil {
+		return nil, err
+	}
+	return verificationKeyFor(alg, key)
+}
+
+// refresh reads the discovery document and swaps the key set if its jwks_uri changed.
+func (provider *oidcProvider) refresh(ctx context.Context) error {
+	doc, err := provider.discover(ctx)
+	if err != nil {
+		return err
+	}
+	algorithms := slices.DeleteFunc(doc.IDTokenSigningAlgValuesSupported, func(alg string) bool {
+		return alg == "none"
+	})
+	old := provider.state.Load()
+	if old != nil && old.jwksURI == doc.JWKSURI {
+		provider.state.Store(&oidcState{jwksURI: old.jwksURI, algorithms: algorithms, jwks: old.jwks, cancel: old.cancel})
+		return nil
+	}
+	jwksCtx, cancel := context.WithCancel(ctx)
+	jwks, err := NewJWKS(jwksCtx, JWKSConfig{
+		URL:                 doc.JWKSURI,
+		HTTPClient:          provider.config.HTTPClient,
+		RefreshInterval:     provider.config.JWKSRefreshInterval,
+		RefreshErrorHandler: provider.config.RefreshErrorHandler,
+	})
+	if err != nil {
+		cancel()
+		return fmt.Errorf("oidc: %w", err)
+	}
+	provider.state.Store(&oidcState{jwksURI: doc.JWKSURI, algorithms: algorithms, jwks: jwks, cancel: cancel})
+	if old != nil {
+		old.cancel()
+	}
+	return nil
+}
+
+func (provider *oidcProvider) refreshLoop(ctx context.Context) {
+	ticker := time.NewTicker(provider.config.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := provider.refresh(ctx); err != nil && provider.config.RefreshErrorHandler != nil {
+				provider.config.RefreshErrorHandler(err)
+			}
+		}
+	}
+}
+
+func (provider *oidcProvider) discover(ctx context.Context) (*oidcDiscoveryDocument, error) {
+	discoveryURL := strings.TrimSuffix(provider.config.Issuer, "/") + oidcDiscoveryPath
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := provider.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc: unexpected status code=%v from %v", resp.StatusCode, discoveryURL)
+	}
+	var doc oidcDiscoveryDocument
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("oidc: invalid discovery document: %w", err)
+	}
+	if doc.Issuer != provider.config.Issuer {
+		return nil, fmt.Errorf("oidc: discovery document issuer=%v does not match %v", doc.Issuer, provider.config.Issuer)
+	}
+	if u, err := url.Parse(doc.JWKSURI); err != nil || !u.IsAbs() {
+		return nil, fmt.Errorf("oidc: invalid discovery document jwks_uri=%v", doc.JWKSURI)
+	}
+	if !slices.ContainsFunc(doc.IDTokenSigningAlgValuesSupported, func(alg string) bool { return alg != "none" }) {
+		return nil, errors.New("oidc: discovery document lists no supported signing algorithms")
+	}
+	return &doc, nil
+}
@@ -0,0 +1,155 @@
+package jwt_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ErenDursun/go-grpc-jwt-middleware/jwt"
+	extJwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// oidcServer is an httptest server standing in for an OpenID Connect provider.
+type oidcServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	discovery map[string]any
+}
+
+func newOIDCServer(jwksURI string) *oidcServer {
+	s := &oidcServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/openid-configuration" {
+			http.NotFound(w, r)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(s.discovery)
+	}))
+	s.discovery = map[string]any{
+		"issuer":                                s.URL,
+		"jwks_uri":                              jwksURI,
+		"id_token_signing_alg_values_supported": []string{"RS256", "none"},
+	}
+	return s
+}
+
+func (s *oidcServer) set(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.discovery[key] = value
+}
+
+type OIDCTestSuite struct {
+	suite.Suite
+
+	key        *rsa.PrivateKey
+	jwksServer *jwksServer
+	oidcServer *oidcServer
+}
+
+func (s *OIDCTestSuite) SetupSuite() {
+	s.key, _ = rsa.GenerateKey(rand.Reader, 2048)
+}
+
+func (s *OIDCTestSuite) SetupTest() {
+	s.jwksServer = newJWKSServer(newJWK("rsa-1", &s.key.PublicKey))
+	s.oidcServer = newOIDCServer(s.jwksServer.URL)
+}
+
+func (s *OIDCTestSuite) TearDownTest() {
+	s.oidcServer.Close()
+	s.jwksServer.Close()
+}
+
+func TestOIDCTestSuite(t *testing.T) {
+	suite.Run(t, new(OIDCTestSuite))
+}
+
+func (s *OIDCTestSuite) TestGoodAuth() {
+	authFunc, err := jwt.NewAuthFuncWithOIDC(context.TODO(), jwt.OIDCConfig{Issuer: s.oidcServer.URL})
+	require.NoError(s.T(), err)
+
+	token := newSignedTokenWithKid(extJwt.SigningMethodRS256, extJwt.MapClaims{"iss": s.oidcServer.URL}, "rsa-1", s.key)
+	_, err = authFunc(incomingCtxWithToken(context.TODO(), "Bearer", token))
+
+	require.NoError(s.T(), err, "no error must occur")
+}
+
+func (s *OIDCTestSuite) TestBadIssuer() {
+	authFunc, err := jwt.NewAuthFuncWithOIDC(context.TODO(), jwt.OIDCConfig{Issuer: s.oidcServer.URL})
+	require.NoError(s.T(), err)
+
+	token := newSignedTokenWithKid(extJwt.SigningMethodRS256, extJwt.MapClaims{"iss": "https://other.example.com"}, "rsa-1", s.key)
+	_, err = authFunc(incomingCtxWithToken(context.TODO(), "Bearer", token))
+
+	assert.Error(s.T(), err, "there must be an error")
+	assert.Equal(s.T(), codes.Unauthenticated, status.Code(err), "must error with unauthenticated")
+}
+
+func (s *OIDCTestSuite) TestUnsupportedAlgorithm() {
+	authFunc, err := jwt.NewAuthFuncWithOIDC(context.TODO(), jwt.OIDCConfig{Issuer: s.oidcServer.URL})
+	require.NoError(s.T(), err)
+
+	token := newSignedTokenWithKid(extJwt.SigningMethodRS384, extJwt.MapClaims{"iss": s.oidcServer.URL}, "rsa-1", s.key)
+	_, err = authFunc(incomingCtxWithToken(context.TODO(), "Bearer", token))
+
+	assert.Error(s.T(), err, "there must be an error")
+	assert.Equal(s.T(), codes.Unauthenticated, status.Code(err), "must error with unauthenticated")
+}
+
+func (s *OIDCTestSuite) TestDiscoveryIssuerMismatch() {
+	s.oidcServer.set("issuer", "https://other.example.com")
+
+	_, err := jwt.NewAuthFuncWithOIDC(context.TODO(), jwt.OIDCConfig{Issuer: s.oidcServer.URL})
+
+	assert.Error(s.T(), err, "there must be an error")
+}
+
+func (s *OIDCTestSuite) TestDiscoveryMissingJWKSURI() {
+	s.oidcServer.set("jwks_uri", "")
+
+	_, err := jwt.NewAuthFuncWithOIDC(context.TODO(), jwt.OIDCConfig{Issuer: s.oidcServer.URL})
+
+	assert.Error(s.T(), err, "there must be an error")
+}
+
+func (s *OIDCTestSuite) TestDiscoveryOnlyNoneAlgorithm() {
+	s.oidcServer.set("id_token_signing_alg_values_supported", []string{"none"})
+
+	_, err := jwt.NewAuthFuncWithOIDC(context.TODO(), jwt.OIDCConfig{Issuer: s.oidcServer.URL})
+
+	assert.Error(s.T(), err, "there must be an error")
+}
+
+func (s *OIDCTestSuite) TestDiscoveryRefresh() {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	authFunc, err := jwt.NewAuthFuncWithOIDC(ctx, jwt.OIDCConfig{Issuer: s.oidcServer.URL, RefreshInterval: 20 * time.Millisecond})
+	require.NoError(s.T(), err)
+
+	rotatedKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rotatedJWKSServer := newJWKSServer(newJWK("rsa-2", &rotatedKey.PublicKey))
+	defer rotatedJWKSServer.Close()
+	s.oidcServer.set("jwks_uri", rotatedJWKSServer.URL)
+	token := newSignedTokenWithKid(extJwt.SigningMethodRS256, extJwt.MapClaims{"iss": s.oidcServer.URL}, "rsa-2", rotatedKey)
+
+	assert.Eventually(s.T(), func() bool {
+		_, err := authFunc(incomingCtxWithToken(context.TODO(), "Bearer", token))
+		return err == nil
+	}, time.Second, 10*time.Millisecond, "new jwks_uri must be picked up by the discovery refresh")
+}
@@ -0,0 +1,218 @@
+package jwt
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+
+	middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// AuthMode defines how calls of a method are authenticated.
+type AuthMode int
+
+const (
+	// AuthRequired rejects calls without a valid token.
+	AuthRequired AuthMode = iota
+	// AuthOptional authenticates calls carrying a token and lets calls without token through unauthenticated.
+	// Calls carrying an invalid token are rejected.
+	AuthOptional
+	// AuthPublic lets all calls through without authentication.
+	AuthPublic
+)
+
+// MethodRule defines the authentication of the methods matching its pattern.
+type MethodRule struct {
+	// Method pattern matched against the full method name.
+	// "/pkg.Service/Method" matches a single method, "/pkg.Service/*" matches all methods of a service and any
+	// other pattern ending in "*" matches all methods with the given prefix, e.g. "/grpc.reflection.*".
+	// If several rules match, an exact match wins over the longest matching prefix.
+	// Required.
+	Method string
+
+	// Mode of authentication.
+	// Optional. Default value AuthRequired.
+	Mode AuthMode
+
+	// RequiredClaims the token must carry. The token's claim must equal the given value or, for array claims,
+	// contain it. A nil value only requires presence of the claim. Calls failing the requirements are rejected
+	// with codes.PermissionDenied. Not checked for unauthenticated calls of AuthOptional methods.
+	// Optional.
+	RequiredClaims map[string]any
+
+	// Scopes the token must be granted, read from the space-delimited scope claim or the scp array claim.
+	// Calls failing the requirement are rejected with codes.PermissionDenied. Not checked for unauthenticated calls
+	// of AuthOptional methods.
+	// Optional.
+	Scopes Requirement
+
+	// Roles the token must be granted, read from the Policy's RoleClaims.
+	// Calls failing the requirement are rejected with codes.PermissionDenied. Not checked for unauthenticated calls
+	// of AuthOptional methods.
+	// Optional.
+	Roles Requirement
+}
+
+// Requirement is satisfied by granted values containing at least one value of AnyOf and all values of AllOf.
+// An empty Requirement is always satisfied.
+type Requirement struct {
+	AnyOf []string
+	AllOf []string
+}
+
+func (requirement Requirement) satisfiedBy(granted []string) bool {
+	if len(requirement.AnyOf) > 0 && !slices.ContainsFunc(requirement.AnyOf, func(v string) bool { return slices.Contains(granted, v) }) {
+		return false
+	}
+	return !slices.ContainsFunc(requirement.AllOf, func(v string) bool { return !slices.Contains(granted, v) })
+}
+
+func (requirement Requirement) isEmpty() bool {
+	return len(requirement.AnyOf) == 0 && len(requirement.AllOf) == 0
+}
+
+// Policy selects the authentication of each method by its full method name.
+type Policy struct {
+	// Rules matched against the full method name.
+	// Optional.
+	Rules []MethodRule
+
+	// DefaultMode of authentication for methods not matching any rule.
+	// Optional. Default value AuthRequired.
+	DefaultMode AuthMode
+
+	// RoleClaims are the paths of the claims holding the token's roles. Nested claims are separated by dots,
+	// e.g. "realm_access.roles". A claim may hold an array or a space-delimited string.
+	// Optional. Default value ["roles"].
+	RoleClaims []string
+}
+
+// compiledPolicy is a Policy prepared for matching on every call.
+type compiledPolicy struct {
+	exact       map[string]*MethodRule
+	prefixes    []*MethodRule
+	defaultRule *MethodRule
+	roleClaims  []string
+}
+
+func (policy Policy) compile() *compiledPolicy {
+	compiled := &compiledPolicy{
+		exact:       make(map[string]*MethodRule),
+		defaultRule: &MethodRule{Mode: policy.DefaultMode},
+		roleClaims:  policy.RoleClaims,
+	}
+	if len(compiled.roleClaims) == 0 {
+		compiled.roleClaims = []string{"roles"}
+	}
+	for _, rule := range policy.Rules {
+		if prefix, ok := strings.CutSuffix(rule.Method, "*"); ok {
+			rule.Method = prefix
+			compiled.prefixes = append(compiled.prefixes, &rule)
+		} else {
+			compiled.exact[rule.Method] = &rule
+		}
+	}
+	sort.SliceStable(compiled.prefixes, func(i, j int) bool {
+		return len(compiled.prefixes[i].Method) > len(compiled.prefixes[j].Method)
+	})
+	return compiled
+}
+
+func (compiled *compiledPolicy) match(fullMethod string) *MethodRule {
+	if rule, ok := compiled.exact[fullMethod]; ok {
+		return rule
+	}
+	for _, rule := range compiled.prefixes {
+		if strings.HasPrefix(fullMethod, rule.Method) {
+			return rule
+		}
+	}
+	return compiled.defaultRule
+}
+
+// authenticate applies the rule matching fullMethod to the call.
+func (compiled *compiledPolicy) authenticate(ctx context.Context, authFunc auth.AuthFunc, fullMethod string) (context.Context, error) {
+	rule := compiled.match(fullMethod)
+	switch rule.Mode {
+	case AuthPublic:
+		return ctx, nil
+	case AuthOptional:
+		if len(metadata.ValueFromIncomingContext(ctx, "authorization")) == 0 {
+			return ctx, nil
+		}
+	}
+	deferred := &deferredValidation{}
+	newCtx, err := authFunc(context.WithValue(ctx, deferredValidationContextKey{}, deferred))
+	if err != nil {
+		return nil, err
+	}
+	if err := compiled.authorize(newCtx, rule); err != nil {
+		return nil, err
+	}
+	if deferred.validate != nil {
+		if err := deferred.validate(); err != nil {
+			return nil, err
+		}
+	}
+	// auth funcs called with the handler's context, e.g. for refreshed tokens, must run their checks themselves
+	return context.WithValue(newCtx, deferredValidationContextKey{}, (*deferredValidation)(nil)), nil
+}
+
+// authorize checks the rule's claim, scope and role requirements against the authenticated token.
+func (compiled *compiledPolicy) authorize(ctx context.Context, rule *MethodRule) error {
+	if len(rule.RequiredClaims) == 0 && rule.Scopes.isEmpty() && rule.Roles.isEmpty() {
+		return nil
+	}
+	token, ok := TokenFromContext(ctx)
+	if !ok {
+		return status.Error(codes.PermissionDenied, "missing token claims")
+	}
+	claims := claimsMap(token.Claims)
+	for name, want := range rule.RequiredClaims {
+		claim, ok := claims[name]
+		if !ok || !claimMatches(claim, want) {
+			return status.Errorf(codes.PermissionDenied, "missing required claim %v", name)
+		}
+	}
+	if !rule.Scopes.satisfiedBy(scopesFromClaims(claims)) {
+		return status.Error(codes.PermissionDenied, "missing required scope")
+	}
+	if !rule.Roles.satisfiedBy(rolesFromClaims(claims, compiled.roleClaims)) {
+		return status.Error(codes.PermissionDenied, "missing required role")
+	}
+	return nil
+}
+
+// UnaryServerInterceptor returns a new unary server interceptor that authenticates calls with authFunc as selected
+// by the policy.
+func (policy Policy) UnaryServerInterceptor(authFunc auth.AuthFunc) grpc.UnaryServerInterceptor {
+	compiled := policy.compile()
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		newCtx, err := compiled.authenticate(ctx, authFunc, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(newCtx, req)
+	}
+}
+
+// StreamServerInterceptor returns a new stream server interceptor that authenticates calls with authFunc as
+// selected by the policy.
+func (policy Policy) StreamServerInterceptor(authFunc auth.AuthFunc) grpc.StreamServerInterceptor {
+	compiled := policy.compile()
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		newCtx, err := compiled.authenticate(stream.Context(), authFunc, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		wrapped := middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = newCtx
+		return handler(srv, wrapped)
+	}
+}
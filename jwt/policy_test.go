@@ -0,0 +1,163 @@
+package jwt_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ErenDursun/go-grpc-jwt-middleware/jwt"
+	extJwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeServerStream implements a fake grpc.ServerStream for the purpose of stream interceptor tests.
+type fakeServerStream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+type PolicyTestSuite struct {
+	suite.Suite
+
+	goodAuthToken   string
+	adminAuthToken  string
+	badAuthToken    string
+	readerAuthToken string
+	writerAuthToken string
+
+	unary  grpc.UnaryServerInterceptor
+	stream grpc.StreamServerInterceptor
+}
+
+func (s *PolicyTestSuite) SetupSuite() {
+	secret := []byte("good_secret")
+	s.goodAuthToken = newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{"sub": "alice", "groups": []string{"users"}}, secret)
+	s.adminAuthToken = newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{"sub": "bob", "groups": []string{"users", "admins"}}, secret)
+	s.badAuthToken = newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{"sub": "eve"}, []byte("bad_secret"))
+	s.readerAuthToken = newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{"sub": "carol", "scope": "openid docs.read"}, secret)
+	s.writerAuthToken = newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{
+		"sub":          "dave",
+		"scp":          []string{"docs.read", "docs.write"},
+		"realm_access": map[string]any{"roles": []string{"editor"}},
+	}, secret)
+
+	policy := jwt.Policy{
+		Rules: []jwt.MethodRule{
+			{Method: "/grpc.health.v1.Health/*", Mode: jwt.AuthPublic},
+			{Method: "/grpc.reflection.*", Mode: jwt.AuthPublic},
+			{Method: "/test.Service/*", Mode: jwt.AuthOptional},
+			{Method: "/test.Service/Login", Mode: jwt.AuthPublic},
+			{Method: "/test.Admin/*", RequiredClaims: map[string]any{"groups": "admins"}},
+			{Method: "/test.Admin/Whoami", RequiredClaims: map[string]any{"sub": nil}},
+			{Method: "/test.Docs/Read", Scopes: jwt.Requirement{AnyOf: []string{"docs.read", "docs.admin"}}},
+			{Method: "/test.Docs/Write", Scopes: jwt.Requirement{AllOf: []string{"docs.read", "docs.write"}}, Roles: jwt.Requirement{AnyOf: []string{"editor"}}},
+		},
+		RoleClaims: []string{"roles", "realm_access.roles"},
+	}
+	authFunc := jwt.NewAuthFunc(secret)
+	s.unary = policy.UnaryServerInterceptor(authFunc)
+	s.stream = policy.StreamServerInterceptor(authFunc)
+}
+
+func TestPolicyTestSuite(t *testing.T) {
+	suite.Run(t, new(PolicyTestSuite))
+}
+
+// callUnary runs the unary interceptor and reports whether the handler saw a token.
+func (s *PolicyTestSuite) callUnary(fullMethod string, token string) (bool, error) {
+	ctx := context.TODO()
+	if token != "" {
+		ctx = incomingCtxWithToken(ctx, "Bearer", token)
+	}
+	var authenticated bool
+	_, err := s.unary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: fullMethod}, func(ctx context.Context, req any) (any, error) {
+		authenticated = ctx.Value(jwt.DefaultContextKey) != nil
+		return nil, nil
+	})
+	return authenticated, err
+}
+
+func (s *PolicyTestSuite) TestPublic() {
+	for _, method := range []string{"/grpc.health.v1.Health/Check", "/grpc.reflection.v1.ServerReflection/Info", "/test.Service/Login"} {
+		authenticated, err := s.callUnary(method, s.badAuthToken)
+		require.NoError(s.T(), err, method)
+		assert.False(s.T(), authenticated, method)
+	}
+}
+
+func (s *PolicyTestSuite) TestOptional() {
+	authenticated, err := s.callUnary("/test.Service/Get", "")
+	require.NoError(s.T(), err)
+	assert.False(s.T(), authenticated)
+
+	authenticated, err = s.callUnary("/test.Service/Get", s.goodAuthToken)
+	require.NoError(s.T(), err)
+	assert.True(s.T(), authenticated)
+
+	_, err = s.callUnary("/test.Service/Get", s.badAuthToken)
+	assert.Equal(s.T(), codes.Unauthenticated, status.Code(err), "invalid tokens must be rejected")
+}
+
+func (s *PolicyTestSuite) TestRequiredByDefault() {
+	_, err := s.callUnary("/test.Other/Get", "")
+	assert.Equal(s.T(), codes.Unauthenticated, status.Code(err), "must error with unauthenticated")
+
+	authenticated, err := s.callUnary("/test.Other/Get", s.goodAuthToken)
+	require.NoError(s.T(), err)
+	assert.True(s.T(), authenticated)
+}
+
+func (s *PolicyTestSuite) TestRequiredClaims() {
+	_, err := s.callUnary("/test.Admin/Delete", s.goodAuthToken)
+	assert.Equal(s.T(), codes.PermissionDenied, status.Code(err), "must error with permission denied")
+
+	_, err = s.callUnary("/test.Admin/Delete", s.adminAuthToken)
+	require.NoError(s.T(), err)
+
+	_, err = s.callUnary("/test.Admin/Whoami", s.goodAuthToken)
+	require.NoError(s.T(), err, "exact rule must take precedence over service wildcard")
+}
+
+func (s *PolicyTestSuite) TestScopes() {
+	_, err := s.callUnary("/test.Docs/Read", s.goodAuthToken)
+	assert.Equal(s.T(), codes.PermissionDenied, status.Code(err), "must error with permission denied")
+
+	_, err = s.callUnary("/test.Docs/Read", s.readerAuthToken)
+	require.NoError(s.T(), err, "scope claim must grant scopes")
+
+	_, err = s.callUnary("/test.Docs/Read", s.writerAuthToken)
+	require.NoError(s.T(), err, "scp claim must grant scopes")
+}
+
+func (s *PolicyTestSuite) TestScopesAndRoles() {
+	_, err := s.callUnary("/test.Docs/Write", s.readerAuthToken)
+	assert.Equal(s.T(), codes.PermissionDenied, status.Code(err), "must error with permission denied")
+
+	_, err = s.callUnary("/test.Docs/Write", s.writerAuthToken)
+	require.NoError(s.T(), err, "nested role claim must grant roles")
+}
+
+func (s *PolicyTestSuite) TestStream() {
+	handler := func(srv any, stream grpc.ServerStream) error {
+		if stream.Context().Value(jwt.DefaultContextKey) == nil {
+			return status.Error(codes.Internal, "no token")
+		}
+		return nil
+	}
+
+	err := s.stream(nil, &fakeServerStream{ctx: context.TODO()}, &grpc.StreamServerInfo{FullMethod: "/test.Admin/Watch"}, handler)
+	assert.Equal(s.T(), codes.Unauthenticated, status.Code(err), "must error with unauthenticated")
+
+	ctx := incomingCtxWithToken(context.TODO(), "Bearer", s.adminAuthToken)
+	err = s.stream(nil, &fakeServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: "/test.Admin/Watch"}, handler)
+	require.NoError(s.T(), err)
+}
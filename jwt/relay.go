@@ -0,0 +1,111 @@
+package jwt
+
+import (
+	"context"
+	"slices"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// RelayConfig defines the config for forwarding the caller's verified token to downstream calls.
+type RelayConfig struct {
+	// ContextKey the verified token is stored under, see Config.ContextKey.
+	// Optional. Defaults to the token of the authenticated call, regardless of the Config's ContextKey.
+	ContextKey ContextKey
+
+	// Targets receiving the token, matched against the target of the client connection, e.g. "dns:///orders:8080".
+	// A pattern ending in "*" matches all targets with the given prefix. No target receives the token if empty.
+	// Required.
+	Targets []string
+
+	// Methods receiving the token, using the patterns of MethodRule.Method.
+	// Optional. All methods of the Targets receive the token if empty.
+	Methods []string
+
+	// AuthScheme of the outgoing authorization metadata.
+	// Optional. Default value "Bearer".
+	AuthScheme string
+
+	// Downscope returns the token attached instead of the caller's token, e.g. a token exchanged for the target
+	// audience with fewer scopes. An error fails the call, with codes.Unauthenticated unless it is a gRPC status.
+	// Optional.
+	Downscope func(ctx context.Context, token *jwt.Token, target string, fullMethod string) (string, error)
+}
+
+// UnaryClientInterceptor returns a new unary client interceptor attaching the caller's token to calls of the
+// allowed targets and methods. Calls already carrying authorization metadata are left untouched.
+func (config RelayConfig) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	config.setDefaults()
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		newCtx, err := config.relay(ctx, cc.Target(), method)
+		if err != nil {
+			return err
+		}
+		return invoker(newCtx, method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor returns a new stream client interceptor attaching the caller's token to calls of the
+// allowed targets and methods. Calls already carrying authorization metadata are left untouched.
+func (config RelayConfig) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	config.setDefaults()
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		newCtx, err := config.relay(ctx, cc.Target(), method)
+		if err != nil {
+			return nil, err
+		}
+		return streamer(newCtx, desc, cc, method, opts...)
+	}
+}
+
+func (config *RelayConfig) setDefaults() {
+	if config.AuthScheme == "" {
+		config.AuthScheme = "Bearer"
+	}
+}
+
+// relay attaches the token to the outgoing context if the call is allowed to receive it.
+func (config *RelayConfig) relay(ctx context.Context, target string, fullMethod string) (context.Context, error) {
+	if !config.allowed(target, fullMethod) {
+		return ctx, nil
+	}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get("authorization")) > 0 {
+		return ctx, nil
+	}
+	token, ok := config.token(ctx)
+	if !ok {
+		return ctx, nil
+	}
+	raw := token.Raw
+	if config.Downscope != nil {
+		var err error
+		if raw, err = config.Downscope(ctx, token, target, fullMethod); err != nil {
+			if _, ok := status.FromError(err); ok {
+				return nil, err
+			}
+			return nil, status.Errorf(codes.Unauthenticated, "token downscoping failed: %v", err)
+		}
+	}
+	return metadata.AppendToOutgoingContext(ctx, "authorization", config.AuthScheme+" "+raw), nil
+}
+
+func (config *RelayConfig) allowed(target string, fullMethod string) bool {
+	if !slices.ContainsFunc(config.Targets, func(pattern string) bool { return methodMatches(pattern, target) }) {
+		return false
+	}
+	return len(config.Methods) == 0 || slices.ContainsFunc(config.Methods, func(pattern string) bool {
+		return methodMatches(pattern, fullMethod)
+	})
+}
+
+func (config *RelayConfig) token(ctx context.Context) (*jwt.Token, bool) {
+	if config.ContextKey == "" {
+		return TokenFromContext(ctx)
+	}
+	token, ok := ctx.Value(config.ContextKey).(*jwt.Token)
+	return token, ok && token != nil && token.Raw != ""
+}
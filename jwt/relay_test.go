@@ -0,0 +1,161 @@
+package jwt_test
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"testing"
+
+	"github.com/ErenDursun/go-grpc-jwt-middleware/jwt"
+	extJwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type RelayTestSuite struct {
+	suite.Suite
+
+	secret   []byte
+	token    string
+	ctx      context.Context
+	received chan []string
+	dialer   func(context.Context, string) (net.Conn, error)
+}
+
+func (s *RelayTestSuite) SetupSuite() {
+	s.secret = []byte("good_secret")
+	s.token = newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{"sub": "alice"}, s.secret)
+	s.received = make(chan []string, 16)
+
+	srv := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(srv, &assertingServer{assertFunc: func(ctx context.Context) {
+		s.received <- metadata.ValueFromIncomingContext(ctx, "authorization")
+	}})
+	const bufSize = 1024 * 1024
+	lis := bufconn.Listen(bufSize)
+	go func() {
+		if err := srv.Serve(lis); err != nil {
+			log.Fatalf("Server exited with error: %v", err)
+		}
+	}()
+	s.T().Cleanup(srv.Stop)
+	s.dialer = func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}
+}
+
+func (s *RelayTestSuite) SetupTest() {
+	// the gateway's context after authenticating the caller
+	authFunc := jwt.NewAuthFunc(s.secret)
+	ctx, err := authFunc(incomingCtxWithToken(context.TODO(), "Bearer", s.token))
+	require.NoError(s.T(), err)
+	s.ctx = ctx
+}
+
+func TestRelayTestSuite(t *testing.T) {
+	suite.Run(t, new(RelayTestSuite))
+}
+
+func (s *RelayTestSuite) newClient(target string, config jwt.RelayConfig) grpc_health_v1.HealthClient {
+	conn, err := grpc.NewClient(target,
+		grpc.WithContextDialer(s.dialer),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(config.UnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(config.StreamClientInterceptor()),
+	)
+	require.NoError(s.T(), err)
+	s.T().Cleanup(func() { _ = conn.Close() })
+	return grpc_health_v1.NewHealthClient(conn)
+}
+
+func (s *RelayTestSuite) check(client grpc_health_v1.HealthClient, ctx context.Context) []string {
+	_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(s.T(), err)
+	return <-s.received
+}
+
+func (s *RelayTestSuite) TestRelay() {
+	client := s.newClient("passthrough:///orders", jwt.RelayConfig{Targets: []string{"passthrough:///orders"}})
+
+	assert.Equal(s.T(), []string{"Bearer " + s.token}, s.check(client, s.ctx))
+	assert.Empty(s.T(), s.check(client, context.TODO()), "unauthenticated calls must not carry a token")
+}
+
+func (s *RelayTestSuite) TestStream() {
+	client := s.newClient("passthrough:///orders", jwt.RelayConfig{Targets: []string{"passthrough:///*"}})
+
+	stream, err := client.Watch(s.ctx, &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(s.T(), err)
+	_, err = stream.Recv()
+	require.NoError(s.T(), err)
+
+	assert.Equal(s.T(), []string{"Bearer " + s.token}, <-s.received)
+}
+
+func (s *RelayTestSuite) TestAllowLists() {
+	client := s.newClient("passthrough:///untrusted", jwt.RelayConfig{Targets: []string{"passthrough:///orders"}})
+	assert.Empty(s.T(), s.check(client, s.ctx), "targets not on the allow-list must not receive the token")
+
+	client = s.newClient("passthrough:///orders", jwt.RelayConfig{Targets: []string{"passthrough:///orders"}, Methods: []string{"/grpc.health.v1.Health/Watch"}})
+	assert.Empty(s.T(), s.check(client, s.ctx), "methods not on the allow-list must not receive the token")
+
+	client = s.newClient("passthrough:///orders", jwt.RelayConfig{})
+	assert.Empty(s.T(), s.check(client, s.ctx), "no target must receive the token by default")
+}
+
+func (s *RelayTestSuite) TestExistingAuthorization() {
+	client := s.newClient("passthrough:///orders", jwt.RelayConfig{Targets: []string{"passthrough:///orders"}})
+	ctx := metadata.AppendToOutgoingContext(s.ctx, "authorization", "Bearer service-token")
+
+	assert.Equal(s.T(), []string{"Bearer service-token"}, s.check(client, ctx))
+}
+
+func (s *RelayTestSuite) TestDownscope() {
+	var target, method string
+	client := s.newClient("passthrough:///orders", jwt.RelayConfig{
+		Targets: []string{"passthrough:///orders"},
+		Downscope: func(ctx context.Context, token *extJwt.Token, t string, m string) (string, error) {
+			target, method = t, m
+			sub, _ := token.Claims.GetSubject()
+			return "downscoped-for-" + sub, nil
+		},
+	})
+
+	assert.Equal(s.T(), []string{"Bearer downscoped-for-alice"}, s.check(client, s.ctx))
+	assert.Equal(s.T(), "passthrough:///orders", target)
+	assert.Equal(s.T(), "/grpc.health.v1.Health/Check", method)
+}
+
+func (s *RelayTestSuite) TestDownscopeError() {
+	client := s.newClient("passthrough:///orders", jwt.RelayConfig{
+		Targets: []string{"passthrough:///orders"},
+		Downscope: func(context.Context, *extJwt.Token, string, string) (string, error) {
+			return "", errors.New("exchange failed")
+		},
+	})
+
+	_, err := client.Check(s.ctx, &grpc_health_v1.HealthCheckRequest{})
+
+	assert.Equal(s.T(), codes.Unauthenticated, status.Code(err), "must error with unauthenticated")
+}
+
+func (s *RelayTestSuite) TestContextKey() {
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{SigningKey: s.secret, ContextKey: "caller"})
+	ctx, err := authFunc(incomingCtxWithToken(context.TODO(), "Bearer", s.token))
+	require.NoError(s.T(), err)
+	client := s.newClient("passthrough:///orders", jwt.RelayConfig{ContextKey: "caller", Targets: []string{"passthrough:///orders"}})
+
+	assert.Equal(s.T(), []string{"Bearer " + s.token}, s.check(client, ctx))
+
+	client = s.newClient("passthrough:///orders", jwt.RelayConfig{ContextKey: "other", Targets: []string{"passthrough:///orders"}})
+	assert.Empty(s.T(), s.check(client, ctx))
+}
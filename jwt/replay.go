@@ -0,0 +1,71 @@
+package jwt
+
+import (
+	"context"
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+// ReplayStore records the ids of one-time-use tokens until they expire.
+type ReplayStore interface {
+	// MarkUsed records id until expiresAt and reports whether it had already been recorded.
+	// Implementations must be safe for concurrent use, and concurrent calls for the same id must report exactly one
+	// first use.
+	MarkUsed(ctx context.Context, id string, expiresAt time.Time) (alreadyUsed bool, err error)
+}
+
+// DefaultReplayStoreShards is the default number of shards of a MemoryReplayStore.
+const DefaultReplayStoreShards = 64
+
+// MemoryReplayStore is an in-memory ReplayStore. Ids are spread over independently locked shards to keep lock
+// contention low under heavy concurrent traffic, and are dropped once expired.
+type MemoryReplayStore struct {
+	seed   maphash.Seed
+	shards []replayShard
+}
+
+type replayShard struct {
+	mu  sync.Mutex
+	ids *expiringMap[string, struct{}]
+}
+
+// NewMemoryReplayStore returns an empty MemoryReplayStore with the given number of shards.
+// A non-positive number of shards defaults to DefaultReplayStoreShards.
+func NewMemoryReplayStore(shards int) *MemoryReplayStore {
+	if shards <= 0 {
+		shards = DefaultReplayStoreShards
+	}
+	store := &MemoryReplayStore{seed: maphash.MakeSeed(), shards: make([]replayShard, shards)}
+	for i := range store.shards {
+		store.shards[i].ids = newExpiringMap[string, struct{}]()
+	}
+	return store
+}
+
+// MarkUsed implements ReplayStore.
+func (store *MemoryReplayStore) MarkUsed(ctx context.Context, id string, expiresAt time.Time) (bool, error) {
+	shard := &store.shards[maphash.String(store.seed, id)%uint64(len(store.shards))]
+	now := time.Now()
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	if _, ok := shard.ids.get(id, now); ok {
+		return true, nil
+	}
+	shard.ids.set(id, struct{}{}, expiresAt, now)
+	return false, nil
+}
+
+// Len returns the number of unexpired ids.
+func (store *MemoryReplayStore) Len() int {
+	now := time.Now()
+	n := 0
+	for i := range store.shards {
+		shard := &store.shards[i]
+		shard.mu.Lock()
+		n += shard.ids.len(now)
+		shard.mu.Unlock()
+	}
+	return n
+}
@@ -0,0 +1,197 @@
+package jwt_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ErenDursun/go-grpc-jwt-middleware/jwt"
+	extJwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type ReplayTestSuite struct {
+	suite.Suite
+
+	secret   []byte
+	store    *jwt.MemoryReplayStore
+	authFunc func(context.Context) (context.Context, error)
+}
+
+func (s *ReplayTestSuite) SetupTest() {
+	s.secret = []byte("good_secret")
+	s.store = jwt.NewMemoryReplayStore(0)
+	s.authFunc = jwt.NewAuthFuncWithConfig(jwt.Config{SigningKey: s.secret, ReplayStore: s.store})
+}
+
+func TestReplayTestSuite(t *testing.T) {
+	suite.Run(t, new(ReplayTestSuite))
+}
+
+func (s *ReplayTestSuite) auth(token string) error {
+	_, err := s.authFunc(incomingCtxWithToken(context.TODO(), "Bearer", token))
+	return err
+}
+
+func (s *ReplayTestSuite) TestSingleUse() {
+	token := newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{"jti": "job-1", "exp": float64(time.Now().Add(time.Minute).Unix())}, s.secret)
+
+	require.NoError(s.T(), s.auth(token))
+	err := s.auth(token)
+
+	assert.Equal(s.T(), codes.Unauthenticated, status.Code(err), "must error with unauthenticated")
+	assert.Equal(s.T(), "invalid token: already used", status.Convert(err).Message())
+}
+
+func (s *ReplayTestSuite) TestMissingClaims() {
+	err := s.auth(newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{"exp": float64(time.Now().Add(time.Minute).Unix())}, s.secret))
+	assert.Equal(s.T(), "invalid token: missing required claim jti", status.Convert(err).Message())
+
+	err = s.auth(newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{"jti": "job-2"}, s.secret))
+	assert.Equal(s.T(), "invalid token: missing required claim exp", status.Convert(err).Message())
+}
+
+func (s *ReplayTestSuite) TestConcurrentReplays() {
+	token := newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{"jti": "job-3", "exp": float64(time.Now().Add(time.Minute).Unix())}, s.secret)
+
+	var accepted atomic.Int32
+	var wg sync.WaitGroup
+	for range 64 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if s.auth(token) == nil {
+				accepted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(s.T(), int32(1), accepted.Load(), "token must be accepted exactly once")
+}
+
+func (s *ReplayTestSuite) TestLeeway() {
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{SigningKey: s.secret, Leeway: time.Minute, ReplayStore: s.store})
+	// expired, but still accepted within the leeway
+	token := newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{"jti": "job-4", "exp": float64(time.Now().Add(-10 * time.Second).Unix())}, s.secret)
+
+	_, err := authFunc(incomingCtxWithToken(context.TODO(), "Bearer", token))
+	require.NoError(s.T(), err)
+	_, err = authFunc(incomingCtxWithToken(context.TODO(), "Bearer", token))
+	assert.Equal(s.T(), "invalid token: already used", status.Convert(err).Message(), "the jti must be kept for the leeway")
+}
+
+func (s *ReplayTestSuite) TestRejectedCallsDontUseUpToken() {
+	var calls atomic.Int32
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{
+		SigningKey:  s.secret,
+		ReplayStore: s.store,
+		Validators: []jwt.Validator{jwt.ValidatorFunc(func(ctx context.Context, token *extJwt.Token) error {
+			if calls.Add(1) == 1 {
+				return status.Error(codes.PermissionDenied, "tenant suspended")
+			}
+			return nil
+		})},
+	})
+	token := newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{"jti": "job-5", "exp": float64(time.Now().Add(time.Minute).Unix())}, s.secret)
+
+	_, err := authFunc(incomingCtxWithToken(context.TODO(), "Bearer", token))
+	assert.Equal(s.T(), codes.PermissionDenied, status.Code(err))
+	_, err = authFunc(incomingCtxWithToken(context.TODO(), "Bearer", token))
+	require.NoError(s.T(), err, "the replay check must run after validators")
+	_, err = authFunc(incomingCtxWithToken(context.TODO(), "Bearer", token))
+	assert.Equal(s.T(), "invalid token: already used", status.Convert(err).Message())
+}
+
+func (s *ReplayTestSuite) TestPolicy() {
+	policy := jwt.Policy{Rules: []jwt.MethodRule{{Method: "/test.Admin/*", RequiredClaims: map[string]any{"groups": "admins"}}}}
+	unary := policy.UnaryServerInterceptor(s.authFunc)
+	call := func(fullMethod string, token string) error {
+		_, err := unary(incomingCtxWithToken(context.TODO(), "Bearer", token), nil, &grpc.UnaryServerInfo{FullMethod: fullMethod}, func(ctx context.Context, req any) (any, error) {
+			// auth funcs called with the handler's context run the replay check themselves
+			_, err := s.authFunc(ctx)
+			assert.Equal(s.T(), "invalid token: already used", status.Convert(err).Message())
+			return nil, nil
+		})
+		return err
+	}
+	token := newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{"jti": "job-6", "exp": float64(time.Now().Add(time.Minute).Unix())}, s.secret)
+
+	assert.Equal(s.T(), codes.PermissionDenied, status.Code(call("/test.Admin/Delete", token)))
+	require.NoError(s.T(), call("/test.Service/Get", token), "calls failing the method's requirements must not use up the token")
+	assert.Equal(s.T(), "invalid token: already used", status.Convert(call("/test.Service/Get", token)).Message())
+}
+
+func (s *ReplayTestSuite) TestCustomParseTokenFunc() {
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{
+		ParseTokenFunc: func(ctx context.Context, auth string) (any, error) {
+			return &extJwt.Token{Raw: auth, Claims: extJwt.MapClaims{"jti": auth, "exp": float64(time.Now().Add(time.Minute).Unix())}, Valid: true}, nil
+		},
+		ReplayStore: s.store,
+	})
+
+	_, err := authFunc(incomingCtxWithToken(context.TODO(), "Bearer", "job-7"))
+	require.NoError(s.T(), err)
+	_, err = authFunc(incomingCtxWithToken(context.TODO(), "Bearer", "job-7"))
+	assert.Equal(s.T(), "invalid token: already used", status.Convert(err).Message())
+}
+
+func (s *ReplayTestSuite) TestMemoryReplayStore() {
+	store := jwt.NewMemoryReplayStore(4)
+	for i := range 100 {
+		used, err := store.MarkUsed(context.TODO(), fmt.Sprintf("id-%d", i), time.Now().Add(50*time.Millisecond))
+		require.NoError(s.T(), err)
+		assert.False(s.T(), used)
+	}
+	used, _ := store.MarkUsed(context.TODO(), "id-1", time.Now().Add(time.Hour))
+	assert.True(s.T(), used)
+	assert.Equal(s.T(), 100, store.Len())
+
+	time.Sleep(100 * time.Millisecond)
+
+	used, _ = store.MarkUsed(context.TODO(), "id-1", time.Now().Add(time.Hour))
+	assert.False(s.T(), used, "expired ids must be forgotten")
+	assert.Equal(s.T(), 1, store.Len(), "expired ids must be dropped")
+}
+
+func (s *ReplayTestSuite) TestMemoryReplayStoreSteadyExpiry() {
+	store := jwt.NewMemoryReplayStore(1)
+	start := time.Now()
+	for i := range 1000 {
+		_, err := store.MarkUsed(context.TODO(), fmt.Sprintf("id-%d", i), start.Add(time.Duration(i)*100*time.Microsecond))
+		require.NoError(s.T(), err)
+	}
+
+	// ids expire one after the other while new ones arrive
+	time.Sleep(50 * time.Millisecond)
+	for i := 1000; i < 1100; i++ {
+		used, _ := store.MarkUsed(context.TODO(), fmt.Sprintf("id-%d", i), time.Now().Add(time.Hour))
+		assert.False(s.T(), used)
+	}
+	assert.Less(s.T(), store.Len(), 1100-400, "expired ids must be dropped")
+	used, _ := store.MarkUsed(context.TODO(), "id-999", time.Now().Add(time.Hour))
+	assert.True(s.T(), used, "unexpired ids must be kept")
+}
+
+// BenchmarkMemoryReplayStoreSteadyExpiry measures MarkUsed on a single shard whose ids expire steadily, so that
+// nearly every call finds expired ids to drop.
+func BenchmarkMemoryReplayStoreSteadyExpiry(b *testing.B) {
+	store := jwt.NewMemoryReplayStore(1)
+	start := time.Now()
+	const ids = 40000
+	for i := range ids {
+		_, _ = store.MarkUsed(context.TODO(), fmt.Sprintf("warm-%d", i), start.Add(time.Duration(i)*2*time.Second/ids))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = store.MarkUsed(context.TODO(), fmt.Sprintf("id-%d", i), time.Now().Add(2*time.Second))
+	}
+}
@@ -0,0 +1,108 @@
+package jwt
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// RevocationStore decides whether a token has been revoked, e.g. after a logout or a compromise.
+// It is consulted by the default ParseTokenFunc after the token's signature and claims have been verified.
+type RevocationStore interface {
+	// IsRevoked reports whether the verified token has been revoked.
+	// An error rejects the token with codes.Unavailable.
+	IsRevoked(ctx context.Context, token *jwt.Token) (bool, error)
+}
+
+// MemoryRevocationStore is an in-memory RevocationStore denying tokens by jti, by sub issued before a cutoff or
+// by sid (session id). Entries are dropped once the revoked tokens have expired, so the store doesn't grow
+// without bound.
+type MemoryRevocationStore struct {
+	leeway time.Duration
+
+	mu sync.RWMutex
+	// tokens and sessions hold no values, subjects hold the issuedBefore cutoff.
+	tokens   *expiringMap[string, struct{}]
+	subjects *expiringMap[string, time.Time]
+	sessions *expiringMap[string, struct{}]
+}
+
+// NewMemoryRevocationStore returns an empty MemoryRevocationStore for tokens validated without Config.Leeway.
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return NewMemoryRevocationStoreWithLeeway(0)
+}
+
+// NewMemoryRevocationStoreWithLeeway returns an empty MemoryRevocationStore keeping entries for leeway beyond the
+// given expiry, as tokens are still accepted until their exp plus Config.Leeway. leeway must be at least the
+// Config's Leeway.
+func NewMemoryRevocationStoreWithLeeway(leeway time.Duration) *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		leeway:   leeway,
+		tokens:   newExpiringMap[string, struct{}](),
+		subjects: newExpiringMap[string, time.Time](),
+		sessions: newExpiringMap[string, struct{}](),
+	}
+}
+
+// RevokeToken denies the token with the given jti. The entry is kept until the token's expiresAt plus the
+// store's leeway.
+func (store *MemoryRevocationStore) RevokeToken(jti string, expiresAt time.Time) {
+	until := expiresAt.Add(store.leeway)
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.tokens.set(jti, struct{}{}, until, time.Now())
+}
+
+// RevokeSubject denies all tokens of the given sub issued before issuedBefore, as well as tokens of the sub
+// without iat claim. The entry is kept until until, which should be the latest expiry of the affected tokens,
+// e.g. issuedBefore plus the maximum token lifetime, and is extended by the store's leeway.
+func (store *MemoryRevocationStore) RevokeSubject(sub string, issuedBefore time.Time, until time.Time) {
+	until = until.Add(store.leeway)
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.subjects.set(sub, issuedBefore, until, time.Now())
+}
+
+// RevokeSession denies all tokens carrying the given sid claim. The entry is kept until until, which should be
+// the latest expiry of the session's tokens, and is extended by the store's leeway.
+func (store *MemoryRevocationStore) RevokeSession(sid string, until time.Time) {
+	until = until.Add(store.leeway)
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.sessions.set(sid, struct{}{}, until, time.Now())
+}
+
+// IsRevoked implements RevocationStore.
+func (store *MemoryRevocationStore) IsRevoked(ctx context.Context, token *jwt.Token) (bool, error) {
+	claims := claimsMap(token.Claims)
+	jti, _ := claims["jti"].(string)
+	sub, _ := token.Claims.GetSubject()
+	sid, _ := claims["sid"].(string)
+	now := time.Now()
+
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	if _, ok := store.tokens.get(jti, now); ok && jti != "" {
+		return true, nil
+	}
+	if _, ok := store.sessions.get(sid, now); ok && sid != "" {
+		return true, nil
+	}
+	if issuedBefore, ok := store.subjects.get(sub, now); ok && sub != "" {
+		iat, err := token.Claims.GetIssuedAt()
+		if err != nil || iat == nil || iat.Before(issuedBefore) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Len returns the number of unexpired entries.
+func (store *MemoryRevocationStore) Len() int {
+	now := time.Now()
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return store.tokens.len(now) + store.subjects.len(now) + store.sessions.len(now)
+}
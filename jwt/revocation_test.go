@@ -0,0 +1,135 @@
+package jwt_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ErenDursun/go-grpc-jwt-middleware/jwt"
+	extJwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// failingRevocationStore implements a jwt.RevocationStore that is unavailable.
+type failingRevocationStore struct{}
+
+func (failingRevocationStore) IsRevoked(context.Context, *extJwt.Token) (bool, error) {
+	return false, errors.New("unavailable")
+}
+
+type RevocationTestSuite struct {
+	suite.Suite
+
+	secret []byte
+	store  *jwt.MemoryRevocationStore
+	auth   func(claims extJwt.MapClaims) error
+}
+
+func (s *RevocationTestSuite) SetupTest() {
+	s.secret = []byte("good_secret")
+	s.store = jwt.NewMemoryRevocationStore()
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{SigningKey: s.secret, RevocationStore: s.store})
+	s.auth = func(claims extJwt.MapClaims) error {
+		_, err := authFunc(incomingCtxWithToken(context.TODO(), "Bearer", newSignedToken(extJwt.SigningMethodHS256, claims, s.secret)))
+		return err
+	}
+}
+
+func TestRevocationTestSuite(t *testing.T) {
+	suite.Run(t, new(RevocationTestSuite))
+}
+
+func (s *RevocationTestSuite) assertRevoked(err error) {
+	assert.Equal(s.T(), codes.Unauthenticated, status.Code(err), "must error with unauthenticated")
+	assert.Equal(s.T(), "invalid token: revoked", status.Convert(err).Message())
+}
+
+func (s *RevocationTestSuite) TestRevokeToken() {
+	s.store.RevokeToken("id-1", time.Now().Add(time.Hour))
+
+	s.assertRevoked(s.auth(extJwt.MapClaims{"jti": "id-1"}))
+	require.NoError(s.T(), s.auth(extJwt.MapClaims{"jti": "id-2"}))
+	require.NoError(s.T(), s.auth(extJwt.MapClaims{}))
+}
+
+func (s *RevocationTestSuite) TestRevokeSubject() {
+	cutoff := time.Now()
+	s.store.RevokeSubject("alice", cutoff, cutoff.Add(time.Hour))
+
+	s.assertRevoked(s.auth(extJwt.MapClaims{"sub": "alice", "iat": float64(cutoff.Add(-time.Minute).Unix())}))
+	s.assertRevoked(s.auth(extJwt.MapClaims{"sub": "alice"}))
+	require.NoError(s.T(), s.auth(extJwt.MapClaims{"sub": "alice", "iat": float64(cutoff.Add(time.Second).Unix())}))
+	require.NoError(s.T(), s.auth(extJwt.MapClaims{"sub": "bob", "iat": float64(cutoff.Add(-time.Minute).Unix())}))
+}
+
+func (s *RevocationTestSuite) TestRevokeSession() {
+	s.store.RevokeSession("session-1", time.Now().Add(time.Hour))
+
+	s.assertRevoked(s.auth(extJwt.MapClaims{"sid": "session-1"}))
+	require.NoError(s.T(), s.auth(extJwt.MapClaims{"sid": "session-2"}))
+}
+
+func (s *RevocationTestSuite) TestExpiredEntriesAreDropped() {
+	s.store.RevokeToken("id-1", time.Now().Add(50*time.Millisecond))
+	s.store.RevokeSession("session-1", time.Now().Add(50*time.Millisecond))
+	assert.Equal(s.T(), 2, s.store.Len())
+
+	time.Sleep(100 * time.Millisecond)
+	s.store.RevokeToken("id-2", time.Now().Add(time.Hour))
+
+	assert.Equal(s.T(), 1, s.store.Len(), "expired entries must be dropped")
+	require.NoError(s.T(), s.auth(extJwt.MapClaims{"jti": "id-1"}))
+}
+
+func (s *RevocationTestSuite) TestExtendedEntriesAreKept() {
+	s.store.RevokeToken("id-1", time.Now().Add(50*time.Millisecond))
+	s.store.RevokeToken("id-1", time.Now().Add(time.Hour))
+
+	time.Sleep(100 * time.Millisecond)
+	s.store.RevokeToken("id-2", time.Now().Add(time.Hour))
+
+	assert.Equal(s.T(), 2, s.store.Len(), "entries revoked again must be kept until their latest expiry")
+	s.assertRevoked(s.auth(extJwt.MapClaims{"jti": "id-1"}))
+}
+
+func (s *RevocationTestSuite) TestLeeway() {
+	store := jwt.NewMemoryRevocationStoreWithLeeway(time.Minute)
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{SigningKey: s.secret, Leeway: time.Minute, RevocationStore: store})
+	expiresAt := time.Now().Add(-10 * time.Second)
+	store.RevokeToken("id-1", expiresAt)
+	store.RevokeSession("session-1", expiresAt)
+
+	// still accepted by the parser, so the entries must still count
+	for _, claims := range []extJwt.MapClaims{{"jti": "id-1"}, {"sid": "session-1"}} {
+		claims["exp"] = float64(expiresAt.Unix())
+		_, err := authFunc(incomingCtxWithToken(context.TODO(), "Bearer", newSignedToken(extJwt.SigningMethodHS256, claims, s.secret)))
+		s.assertRevoked(err)
+	}
+	assert.Equal(s.T(), 2, store.Len())
+}
+
+func (s *RevocationTestSuite) TestCustomParseTokenFunc() {
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{
+		ParseTokenFunc: func(ctx context.Context, auth string) (any, error) {
+			return &extJwt.Token{Raw: auth, Claims: extJwt.MapClaims{"jti": auth}, Valid: true}, nil
+		},
+		RevocationStore: s.store,
+	})
+	s.store.RevokeToken("id-1", time.Now().Add(time.Hour))
+
+	_, err := authFunc(incomingCtxWithToken(context.TODO(), "Bearer", "id-1"))
+	s.assertRevoked(err)
+}
+
+func (s *RevocationTestSuite) TestStoreUnavailable() {
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{SigningKey: s.secret, RevocationStore: failingRevocationStore{}})
+
+	_, err := authFunc(incomingCtxWithToken(context.TODO(), "Bearer", newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{}, s.secret)))
+
+	assert.Equal(s.T(), codes.Unavailable, status.Code(err), "must fail closed with unavailable")
+}
@@ -0,0 +1,274 @@
+package jwt
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// StreamExpiryConfig defines the config for enforcing the expiry of the token a stream was opened with.
+// Authentication only checks the token when the stream opens, so a long-lived stream would otherwise outlive it.
+type StreamExpiryConfig struct {
+	// Leeway added to the token's exp before the stream is ended.
+	// Optional.
+	Leeway time.Duration
+
+	// RefreshToken returns the refreshed token carried by a message received from the client, e.g. a dedicated
+	// message of a oneof. Messages carrying a token are consumed and not passed to the handler. A valid refreshed
+	// token of the same sub extends the stream until its own exp, an invalid one ends the stream. The stream's
+	// context keeps the token the stream was opened with. DPoP-bound tokens can't be refreshed this way: the
+	// refreshed token is passed to AuthFunc with AuthScheme and the stream's original dpop proof, whose ath doesn't
+	// match it, so it is always rejected.
+	// Optional. Tokens cannot be refreshed if nil.
+	RefreshToken func(msg any) (string, bool)
+
+	// AuthFunc validating refreshed tokens, e.g. the one returned by NewAuthFuncWithConfig. It is called with the
+	// stream's context whose authorization metadata is replaced by the refreshed token.
+	// Required if RefreshToken is set.
+	AuthFunc auth.AuthFunc
+
+	// AuthScheme the refreshed token is passed to AuthFunc with.
+	// Optional. Default value "Bearer".
+	AuthScheme string
+
+	// Policy whose requirements for the stream's method, i.e. RequiredClaims, Scopes and Roles, refreshed tokens
+	// must meet, as the token the stream was opened with did. It should be the Policy authenticating the stream.
+	// A refreshed token failing them ends the stream with codes.PermissionDenied.
+	// Optional.
+	Policy *Policy
+}
+
+// StreamServerInterceptor returns a new stream server interceptor that ends streams with codes.Unauthenticated
+// once the token they were opened with expires. It must run after the authenticating interceptor. Streams without
+// token, e.g. of public methods, and tokens without exp are left untouched.
+//
+// On expiry the handler's context is canceled and its sends and receives fail, including a receive it is blocked
+// in. The stream ends once the handler returns, with codes.Unauthenticated regardless of the handler's error.
+func (config StreamExpiryConfig) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	if config.AuthScheme == "" {
+		config.AuthScheme = "Bearer"
+	}
+	var compiled *compiledPolicy
+	if config.Policy != nil {
+		compiled = config.Policy.compile()
+	}
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		token, ok := TokenFromContext(stream.Context())
+		if !ok {
+			return handler(srv, stream)
+		}
+		exp, err := token.Claims.GetExpirationTime()
+		if err != nil || exp == nil {
+			return handler(srv, stream)
+		}
+		sub, _ := token.Claims.GetSubject()
+
+		ctx, cancel := context.WithCancel(stream.Context())
+		defer cancel()
+		expiring := &expiringStream{
+			WrappedServerStream: &middleware.WrappedServerStream{ServerStream: stream, WrappedContext: ctx},
+			config:              &config,
+			policy:              compiled,
+			fullMethod:          info.FullMethod,
+			sub:                 sub,
+			cancel:              cancel,
+			failed:              make(chan struct{}),
+			deadline:            exp.Time.Add(config.Leeway),
+			received:            make(chan error, 1),
+			receives:            make(chan any),
+			stop:                make(chan struct{}),
+		}
+		expiring.timer = time.AfterFunc(time.Until(expiring.deadline), expiring.expire)
+		defer expiring.timer.Stop()
+		defer close(expiring.stop)
+
+		err = handler(srv, expiring)
+		if failure := expiring.err(); failure != nil {
+			return failure
+		}
+		return err
+	}
+}
+
+// expiringStream is a grpc.ServerStream failing once its token expires or a refreshed token is rejected.
+type expiringStream struct {
+	*middleware.WrappedServerStream
+
+	config     *StreamExpiryConfig
+	policy     *compiledPolicy
+	fullMethod string
+	sub        string
+	cancel     context.CancelFunc
+	timer      *time.Timer
+	failed     chan struct{}
+
+	// receives are handed to a single receiver goroutine, so that a receive blocked when the stream fails can be
+	// abandoned. It is started with the first receive and stops when the interceptor returns.
+	startReceiver sync.Once
+	receives      chan any
+	received      chan error
+	stop          chan struct{}
+
+	mu       sync.Mutex
+	deadline time.Time
+	failure  error
+}
+
+// expire fails the stream unless its deadline was extended by a refreshed token in the meantime.
+func (stream *expiringStream) expire() {
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	if time.Now().Before(stream.deadline) {
+		return
+	}
+	stream.failLocked(status.Error(codes.Unauthenticated, "invalid token: expired"))
+}
+
+func (stream *expiringStream) fail(err error) {
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	stream.failLocked(err)
+}
+
+// failLocked ends the stream with err. The caller must hold the lock.
+func (stream *expiringStream) failLocked(err error) {
+	if stream.failure != nil {
+		return
+	}
+	stream.failure = err
+	stream.cancel()
+	close(stream.failed)
+}
+
+func (stream *expiringStream) err() error {
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	return stream.failure
+}
+
+func (stream *expiringStream) SendMsg(m any) error {
+	if err := stream.err(); err != nil {
+		return err
+	}
+	if err := stream.WrappedServerStream.SendMsg(m); err != nil {
+		if failure := stream.err(); failure != nil {
+			return failure
+		}
+		return err
+	}
+	return nil
+}
+
+func (stream *expiringStream) SendHeader(md metadata.MD) error {
+	if err := stream.err(); err != nil {
+		return err
+	}
+	return stream.WrappedServerStream.SendHeader(md)
+}
+
+func (stream *expiringStream) RecvMsg(m any) error {
+	for {
+		if err := stream.recv(m); err != nil {
+			return err
+		}
+		if stream.config.RefreshToken == nil {
+			return nil
+		}
+		refreshed, ok := stream.config.RefreshToken(m)
+		if !ok {
+			return nil
+		}
+		if err := stream.refresh(refreshed); err != nil {
+			stream.fail(err)
+			return err
+		}
+	}
+}
+
+// recv receives the next message into m, or returns early once the stream fails. An abandoned receive keeps
+// running until the stream ends, so m must not be used after an error, as with any failed RecvMsg, and no further
+// receives are made.
+func (stream *expiringStream) recv(m any) error {
+	if err := stream.err(); err != nil {
+		return err
+	}
+	stream.startReceiver.Do(func() {
+		go stream.receive()
+	})
+	select {
+	case stream.receives <- m:
+	case <-stream.failed:
+		return stream.err()
+	}
+	select {
+	case err := <-stream.received:
+		if failure := stream.err(); failure != nil {
+			return failure
+		}
+		return err
+	case <-stream.failed:
+		return stream.err()
+	}
+}
+
+// receive runs the receives handed over by recv until the interceptor returns.
+func (stream *expiringStream) receive() {
+	for {
+		select {
+		case m := <-stream.receives:
+			stream.received <- stream.WrappedServerStream.RecvMsg(m)
+		case <-stream.stop:
+			return
+		}
+	}
+}
+
+// refresh validates a refreshed token and extends the stream until its exp.
+func (stream *expiringStream) refresh(refreshed string) error {
+	if stream.config.AuthFunc == nil {
+		return status.Error(codes.Internal, "token refresh requires an auth func")
+	}
+	md, _ := metadata.FromIncomingContext(stream.ServerStream.Context())
+	md = md.Copy()
+	md.Set("authorization", stream.config.AuthScheme+" "+refreshed)
+	newCtx, err := stream.config.AuthFunc(metadata.NewIncomingContext(stream.ServerStream.Context(), md))
+	if err != nil {
+		return err
+	}
+	token, ok := TokenFromContext(newCtx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "invalid token: refreshed token missing")
+	}
+	if sub, _ := token.Claims.GetSubject(); sub != stream.sub {
+		return status.Error(codes.Unauthenticated, "invalid token: refreshed token of another subject")
+	}
+	exp, err := token.Claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return status.Error(codes.Unauthenticated, "invalid token: refreshed token without exp")
+	}
+	if stream.policy != nil {
+		if err := stream.policy.authorize(newCtx, stream.policy.match(stream.fullMethod)); err != nil {
+			return err
+		}
+	}
+	return stream.extend(exp)
+}
+
+func (stream *expiringStream) extend(exp *jwt.NumericDate) error {
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	if stream.failure != nil {
+		return stream.failure
+	}
+	stream.deadline = exp.Time.Add(stream.config.Leeway)
+	stream.timer.Reset(time.Until(stream.deadline))
+	return nil
+}
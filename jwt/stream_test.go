@@ -0,0 +1,212 @@
+package jwt_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ErenDursun/go-grpc-jwt-middleware/jwt"
+	extJwt "github.com/golang-jwt/jwt/v5"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/recovery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// chatServiceDesc registers a bidirectional stream echoing wrapperspb.StringValue messages. Messages prefixed with
+// "token:" carry refreshed tokens.
+var chatServiceDesc = grpc.ServiceDesc{
+	ServiceName: "test.Chat",
+	HandlerType: (*any)(nil),
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Chat",
+		ServerStreams: true,
+		ClientStreams: true,
+		Handler: func(srv any, stream grpc.ServerStream) error {
+			for {
+				msg := &wrapperspb.StringValue{}
+				if err := stream.RecvMsg(msg); err != nil {
+					if errors.Is(err, io.EOF) {
+						return nil
+					}
+					return err
+				}
+				switch msg.GetValue() {
+				case "panic":
+					panic("handler failed")
+				case "wait":
+					// ignore the client until the stream ends
+					<-stream.Context().Done()
+					return stream.Context().Err()
+				}
+				if err := stream.SendMsg(msg); err != nil {
+					return err
+				}
+			}
+		},
+	}},
+}
+
+type StreamExpiryTestSuite struct {
+	suite.Suite
+
+	secret []byte
+	conn   *grpc.ClientConn
+}
+
+func (s *StreamExpiryTestSuite) SetupSuite() {
+	s.secret = []byte("good_secret")
+	authFunc := jwt.NewAuthFunc(s.secret)
+	policy := jwt.Policy{Rules: []jwt.MethodRule{{Method: "/test.Chat/*", Scopes: jwt.Requirement{AnyOf: []string{"chat"}}}}}
+	expiry := jwt.StreamExpiryConfig{
+		RefreshToken: func(msg any) (string, bool) {
+			return strings.CutPrefix(msg.(*wrapperspb.StringValue).GetValue(), "token:")
+		},
+		AuthFunc: authFunc,
+		Policy:   &policy,
+	}
+
+	srv := grpc.NewServer(grpc.ChainStreamInterceptor(
+		recovery.StreamServerInterceptor(recovery.WithRecoveryHandler(func(p any) error {
+			return status.Errorf(codes.Internal, "%v", p)
+		})),
+		policy.StreamServerInterceptor(authFunc),
+		expiry.StreamServerInterceptor(),
+	))
+	srv.RegisterService(&chatServiceDesc, nil)
+	lis := bufconn.Listen(1024 * 1024)
+	go func() {
+		if err := srv.Serve(lis); err != nil {
+			log.Fatalf("Server exited with error: %v", err)
+		}
+	}()
+	s.T().Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(s.T(), err)
+	s.T().Cleanup(func() { _ = conn.Close() })
+	s.conn = conn
+}
+
+func TestStreamExpiryTestSuite(t *testing.T) {
+	suite.Run(t, new(StreamExpiryTestSuite))
+}
+
+func (s *StreamExpiryTestSuite) newToken(sub string, lifetime time.Duration) string {
+	claims := extJwt.MapClaims{"sub": sub, "scope": "chat"}
+	if lifetime != 0 {
+		claims["exp"] = time.Now().Add(lifetime).Unix()
+	}
+	return newSignedToken(extJwt.SigningMethodHS256, claims, s.secret)
+}
+
+func (s *StreamExpiryTestSuite) open(token string) grpc.ClientStream {
+	ctx, cancel := context.WithCancel(ctxWithToken(context.Background(), "bearer", token))
+	s.T().Cleanup(cancel)
+	stream, err := s.conn.NewStream(ctx, &chatServiceDesc.Streams[0], "/test.Chat/Chat")
+	require.NoError(s.T(), err)
+	return stream
+}
+
+// echo sends value and returns the echoed value or the stream's error.
+func echo(stream grpc.ClientStream, value string) (string, error) {
+	if err := stream.SendMsg(wrapperspb.String(value)); err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	reply := &wrapperspb.StringValue{}
+	if err := stream.RecvMsg(reply); err != nil {
+		return "", err
+	}
+	return reply.GetValue(), nil
+}
+
+func (s *StreamExpiryTestSuite) TestExpiry() {
+	stream := s.open(s.newToken("alice", 2*time.Second))
+
+	reply, err := echo(stream, "hello")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "hello", reply)
+
+	// the handler is blocked receiving when the token expires
+	err = stream.RecvMsg(&wrapperspb.StringValue{})
+	assert.Equal(s.T(), codes.Unauthenticated, status.Code(err))
+	assert.Equal(s.T(), "invalid token: expired", status.Convert(err).Message())
+}
+
+func (s *StreamExpiryTestSuite) TestExpiryWhileWaiting() {
+	stream := s.open(s.newToken("alice", 2*time.Second))
+
+	// the handler only waits for its context to be canceled
+	_, err := echo(stream, "wait")
+	assert.Equal(s.T(), codes.Unauthenticated, status.Code(err))
+	assert.Equal(s.T(), "invalid token: expired", status.Convert(err).Message())
+}
+
+func (s *StreamExpiryTestSuite) TestPanic() {
+	stream := s.open(s.newToken("alice", time.Hour))
+
+	_, err := echo(stream, "panic")
+	assert.Equal(s.T(), codes.Internal, status.Code(err), "panics must reach the recovery interceptor")
+	assert.Equal(s.T(), "handler failed", status.Convert(err).Message())
+}
+
+func (s *StreamExpiryTestSuite) TestWithoutExp() {
+	stream := s.open(s.newToken("alice", 0))
+
+	reply, err := echo(stream, "hello")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "hello", reply)
+	require.NoError(s.T(), stream.CloseSend())
+	assert.ErrorIs(s.T(), stream.RecvMsg(&wrapperspb.StringValue{}), io.EOF)
+}
+
+func (s *StreamExpiryTestSuite) TestRefresh() {
+	stream := s.open(s.newToken("alice", 2*time.Second))
+	require.NoError(s.T(), stream.SendMsg(wrapperspb.String("token:"+s.newToken("alice", time.Hour))))
+
+	time.Sleep(3 * time.Second)
+	reply, err := echo(stream, "still there")
+	require.NoError(s.T(), err, "a refreshed token must keep the stream alive")
+	assert.Equal(s.T(), "still there", reply, "refresh messages must not reach the handler")
+}
+
+func (s *StreamExpiryTestSuite) TestRefreshRejected() {
+	tests := []struct {
+		name    string
+		token   string
+		code    codes.Code
+		message string
+	}{
+		{"another subject", s.newToken("mallory", time.Hour), codes.Unauthenticated, "invalid token: refreshed token of another subject"},
+		{"without exp", s.newToken("alice", 0), codes.Unauthenticated, "invalid token: refreshed token without exp"},
+		{"bad signature", newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{"sub": "alice"}, []byte("bad_secret")), codes.Unauthenticated, ""},
+		{"expired", s.newToken("alice", -time.Minute), codes.Unauthenticated, ""},
+		// e.g. the user lost the scope since opening the stream
+		{"missing scope", newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()}, s.secret), codes.PermissionDenied, "missing required scope"},
+	}
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			stream := s.open(s.newToken("alice", time.Hour))
+
+			_, err := echo(stream, "token:"+tt.token)
+			assert.Equal(s.T(), tt.code, status.Code(err))
+			if tt.message != "" {
+				assert.Equal(s.T(), tt.message, status.Convert(err).Message())
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package jwt
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Validator is a stage of the token validation pipeline. It runs after the token's signature has been verified.
+type Validator interface {
+	// Validate returns an error to reject the token. A gRPC status error is returned to the caller as is,
+	// any other error is mapped to codes.Unauthenticated.
+	Validate(ctx context.Context, token *jwt.Token) error
+}
+
+// ValidatorFunc adapts a function to a Validator.
+type ValidatorFunc func(ctx context.Context, token *jwt.Token) error
+
+// Validate implements Validator.
+func (f ValidatorFunc) Validate(ctx context.Context, token *jwt.Token) error {
+	return f(ctx, token)
+}
+
+// CallInfo describes the gRPC call a token is presented with.
+type CallInfo struct {
+	// FullMethod of the call, e.g. "/pkg.Service/Method". Empty if unknown.
+	FullMethod string
+
+	// Metadata received with the call.
+	Metadata metadata.MD
+
+	// Peer of the call. Nil if unknown.
+	Peer *peer.Peer
+}
+
+// CallInfoFromContext returns the CallInfo of the call handled with the given context.
+func CallInfoFromContext(ctx context.Context) CallInfo {
+	var call CallInfo
+	call.FullMethod, _ = grpc.Method(ctx)
+	call.Metadata, _ = metadata.FromIncomingContext(ctx)
+	call.Peer, _ = peer.FromContext(ctx)
+	return call
+}
+
+// ClaimsValidatorFunc checks a verified token's claims against the call, e.g. a tenant header against the
+// tenant_id claim. It is a Validator whose non-status errors are mapped to codes.PermissionDenied.
+type ClaimsValidatorFunc func(ctx context.Context, token *jwt.Token, call CallInfo) error
+
+// Validate implements Validator.
+func (f ClaimsValidatorFunc) Validate(ctx context.Context, token *jwt.Token) error {
+	err := f(ctx, token, CallInfoFromContext(ctx))
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return status.Error(codes.PermissionDenied, err.Error())
+}
+
+// RevocationValidator returns a Validator rejecting tokens revoked in store.
+// Revoked tokens are rejected with codes.Unauthenticated, store failures with codes.Unavailable.
+func RevocationValidator(store RevocationStore) Validator {
+	return ValidatorFunc(func(ctx context.Context, token *jwt.Token) error {
+		revoked, err := store.IsRevoked(ctx, token)
+		if err != nil {
+			return status.Error(codes.Unavailable, "token revocation check failed")
+		}
+		if revoked {
+			return status.Error(codes.Unauthenticated, "invalid token: revoked")
+		}
+		return nil
+	})
+}
+
+// ReplayValidator returns a Validator accepting each token only once, recording its jti in store until its exp
+// plus leeway, which must be at least the Config's Leeway. Tokens without jti or exp claim and reused tokens are
+// rejected with codes.Unauthenticated, store failures with codes.Unavailable. As it uses up the token, it should run
+// after all other checks.
+func ReplayValidator(store ReplayStore, leeway time.Duration) Validator {
+	return ValidatorFunc(func(ctx context.Context, token *jwt.Token) error {
+		jti, _ := claimsMap(token.Claims)["jti"].(string)
+		if jti == "" {
+			return status.Error(codes.Unauthenticated, "invalid token: missing required claim jti")
+		}
+		exp, err := token.Claims.GetExpirationTime()
+		if err != nil || exp == nil {
+			return status.Error(codes.Unauthenticated, "invalid token: missing required claim exp")
+		}
+		used, err := store.MarkUsed(ctx, jti, exp.Time.Add(leeway))
+		if err != nil {
+			return status.Error(codes.Unavailable, "token replay check failed")
+		}
+		if used {
+			return status.Error(codes.Unauthenticated, "invalid token: already used")
+		}
+		return nil
+	})
+}
+
+// IntrospectionValidator escalates locally verified tokens to the authorization server, so that e.g. revoked
+// tokens are rejected for high-risk methods. If neither Methods nor MarkerClaim is set, every token is introspected.
+type IntrospectionValidator struct {
+	// Introspector used to check the token.
+	// Required.
+	Introspector *Introspector
+
+	// Methods for which the token is introspected, using the patterns of MethodRule.Method. The method is read
+	// from the call's context with grpc.Method.
+	// Optional.
+	Methods []string
+
+	// MarkerClaim whose presence in the token requests introspection regardless of the method.
+	// Optional.
+	MarkerClaim string
+}
+
+// Validate implements Validator.
+func (validator *IntrospectionValidator) Validate(ctx context.Context, token *jwt.Token) error {
+	if !validator.applies(ctx, token) {
+		return nil
+	}
+	_, err := validator.Introspector.Introspect(ctx, token.Raw)
+	return err
+}
+
+func (validator *IntrospectionValidator) applies(ctx context.Context, token *jwt.Token) bool {
+	if len(validator.Methods) == 0 && validator.MarkerClaim == "" {
+		return true
+	}
+	if validator.MarkerClaim != "" {
+		if _, ok := claimsMap(token.Claims)[validator.MarkerClaim]; ok {
+			return true
+		}
+	}
+	fullMethod, ok := grpc.Method(ctx)
+	if !ok {
+		return false
+	}
+	for _, pattern := range validator.Methods {
+		if methodMatches(pattern, fullMethod) {
+			return true
+		}
+	}
+	return false
+}
+
+// methodMatches reports whether fullMethod matches a pattern in the format of MethodRule.Method. It is also used
+// for RelayConfig's target patterns, which share the format.
+func methodMatches(pattern string, fullMethod string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(fullMethod, prefix)
+	}
+	return pattern == fullMethod
+}
+
+// defaultValidators returns the validators configured by RequireCertificateBinding and RevocationStore.
+// The ReplayStore's validator is kept apart, see checkReplay.
+func (config *Config) defaultValidators() []Validator {
+	var validators []Validator
+	if config.RequireCertificateBinding {
+		validators = append(validators, CertificateBindingValidator())
+	}
+	if config.RevocationStore != nil {
+		validators = append(validators, RevocationValidator(config.RevocationStore))
+	}
+	return validators
+}
+
+// runValidators runs the validation pipeline on the value returned by ParseTokenFunc.
+func (config *Config) runValidators(c context.Context, value any) error {
+	if len(config.validators) == 0 {
+		return nil
+	}
+	token, ok := value.(*jwt.Token)
+	if !ok {
+		return status.Error(codes.Internal, "token validators require a *jwt.Token")
+	}
+	for _, validator := range config.validators {
+		if err := validate(c, validator, token); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validate(c context.Context, validator Validator, token *jwt.Token) error {
+	err := validator.Validate(c, token)
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return status.Errorf(codes.Unauthenticated, "invalid token: %v", err)
+}
+
+// deferredValidation carries the replay check of a token out of the auth.AuthFunc, so that a Policy can run it
+// after the method's authorization requirements.
+type deferredValidation struct {
+	validate func() error
+}
+
+// deferredValidationContextKey stores the *deferredValidation of a call authenticated by a Policy.
+type deferredValidationContextKey struct{}
+
+// checkReplay runs the ReplayStore's validator as the last stage of the pipeline, so that calls rejected by any
+// other check don't use up one-time tokens. If a Policy authenticates the call, the check is handed to it.
+func (config *Config) checkReplay(c context.Context, value any) error {
+	if config.replayValidator == nil {
+		return nil
+	}
+	token, ok := value.(*jwt.Token)
+	if !ok {
+		return status.Error(codes.Internal, "token validators require a *jwt.Token")
+	}
+	if deferred, ok := c.Value(deferredValidationContextKey{}).(*deferredValidation); ok && deferred != nil {
+		deferred.validate = func() error { return validate(c, config.replayValidator, token) }
+		return nil
+	}
+	return validate(c, config.replayValidator, token)
+}
@@ -0,0 +1,227 @@
+package jwt_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ErenDursun/go-grpc-jwt-middleware/jwt"
+	extJwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// fakeTransportStream implements a fake grpc.ServerTransportStream making the method available to grpc.Method.
+type fakeTransportStream struct {
+	method string
+}
+
+func (s *fakeTransportStream) Method() string                  { return s.method }
+func (s *fakeTransportStream) SetHeader(md metadata.MD) error  { return nil }
+func (s *fakeTransportStream) SendHeader(md metadata.MD) error { return nil }
+func (s *fakeTransportStream) SetTrailer(md metadata.MD) error { return nil }
+
+type ValidatorTestSuite struct {
+	suite.Suite
+
+	secret       []byte
+	server       *introspectionServer
+	introspector *jwt.Introspector
+}
+
+func (s *ValidatorTestSuite) SetupTest() {
+	s.secret = []byte("good_secret")
+	s.server = newIntrospectionServer()
+	introspector, err := jwt.NewIntrospector(jwt.IntrospectionConfig{
+		Endpoint:         s.server.URL,
+		ClientID:         "api",
+		ClientSecret:     "api_secret",
+		MaxCacheDuration: -1,
+	})
+	require.NoError(s.T(), err)
+	s.introspector = introspector
+}
+
+func (s *ValidatorTestSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func TestValidatorTestSuite(t *testing.T) {
+	suite.Run(t, new(ValidatorTestSuite))
+}
+
+func (s *ValidatorTestSuite) call(authFunc func(context.Context) (context.Context, error), method string, token string) error {
+	ctx := grpc.NewContextWithServerTransportStream(context.TODO(), &fakeTransportStream{method: method})
+	_, err := authFunc(incomingCtxWithToken(ctx, "Bearer", token))
+	return err
+}
+
+func (s *ValidatorTestSuite) TestPipelineOrder() {
+	var stages []string
+	stage := func(name string, err error) jwt.Validator {
+		return jwt.ValidatorFunc(func(ctx context.Context, token *extJwt.Token) error {
+			stages = append(stages, name)
+			return err
+		})
+	}
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{
+		SigningKey: s.secret,
+		Validators: []jwt.Validator{
+			stage("first", nil),
+			stage("second", status.Error(codes.PermissionDenied, "tenant suspended")),
+			stage("third", nil),
+		},
+	})
+	token := newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{"sub": "alice"}, s.secret)
+
+	err := s.call(authFunc, "/test.Service/Get", token)
+
+	assert.Equal(s.T(), codes.PermissionDenied, status.Code(err), "stage status must be returned as is")
+	assert.Equal(s.T(), "tenant suspended", status.Convert(err).Message())
+	assert.Equal(s.T(), []string{"first", "second"}, stages, "pipeline must short-circuit")
+
+	stages = nil
+	err = s.call(authFunc, "/test.Service/Get", newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{}, []byte("bad_secret")))
+	assert.Equal(s.T(), codes.Unauthenticated, status.Code(err), "must error with unauthenticated")
+	assert.Empty(s.T(), stages, "validators must not run for unverified tokens")
+}
+
+func (s *ValidatorTestSuite) TestNonStatusError() {
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{
+		SigningKey: s.secret,
+		Validators: []jwt.Validator{jwt.ValidatorFunc(func(ctx context.Context, token *extJwt.Token) error {
+			return errors.New("unknown tenant")
+		})},
+	})
+
+	err := s.call(authFunc, "/test.Service/Get", newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{}, s.secret))
+
+	assert.Equal(s.T(), codes.Unauthenticated, status.Code(err), "must error with unauthenticated")
+	assert.Equal(s.T(), "invalid token: unknown tenant", status.Convert(err).Message())
+}
+
+func (s *ValidatorTestSuite) TestRevocationBeforeValidators() {
+	store := jwt.NewMemoryRevocationStore()
+	store.RevokeToken("revoked", time.Now().Add(time.Minute))
+	var called bool
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{
+		SigningKey:      s.secret,
+		RevocationStore: store,
+		Validators: []jwt.Validator{jwt.ValidatorFunc(func(ctx context.Context, token *extJwt.Token) error {
+			called = true
+			return nil
+		})},
+	})
+
+	err := s.call(authFunc, "/test.Service/Get", newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{"jti": "revoked"}, s.secret))
+
+	assert.Equal(s.T(), "invalid token: revoked", status.Convert(err).Message())
+	assert.False(s.T(), called)
+}
+
+func (s *ValidatorTestSuite) TestIntrospectionByMethod() {
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{
+		SigningKey: s.secret,
+		Validators: []jwt.Validator{&jwt.IntrospectionValidator{
+			Introspector: s.introspector,
+			Methods:      []string{"/test.Admin/*", "/test.Service/Delete"},
+		}},
+	})
+	token := newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{"sub": "alice"}, s.secret)
+
+	require.NoError(s.T(), s.call(authFunc, "/test.Service/Get", token))
+	assert.Equal(s.T(), int32(0), s.server.requests.Load(), "low-risk methods must be verified locally")
+
+	err := s.call(authFunc, "/test.Service/Delete", token)
+	assert.Equal(s.T(), "invalid token: inactive", status.Convert(err).Message())
+	err = s.call(authFunc, "/test.Admin/Reset", token)
+	assert.Equal(s.T(), codes.Unauthenticated, status.Code(err), "must error with unauthenticated")
+
+	s.server.set(token, map[string]any{"active": true, "sub": "alice"})
+	require.NoError(s.T(), s.call(authFunc, "/test.Admin/Reset", token))
+	assert.Equal(s.T(), int32(3), s.server.requests.Load())
+}
+
+func (s *ValidatorTestSuite) TestIntrospectionByMarkerClaim() {
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{
+		SigningKey: s.secret,
+		Validators: []jwt.Validator{&jwt.IntrospectionValidator{
+			Introspector: s.introspector,
+			Methods:      []string{"/test.Admin/*"},
+			MarkerClaim:  "introspect",
+		}},
+	})
+
+	require.NoError(s.T(), s.call(authFunc, "/test.Service/Get", newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{"sub": "alice"}, s.secret)))
+
+	err := s.call(authFunc, "/test.Service/Get", newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{"sub": "alice", "introspect": true}, s.secret))
+	assert.Equal(s.T(), "invalid token: inactive", status.Convert(err).Message())
+}
+
+func (s *ValidatorTestSuite) TestIntrospectionUnavailable() {
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{
+		SigningKey: s.secret,
+		Validators: []jwt.Validator{&jwt.IntrospectionValidator{Introspector: s.introspector}},
+	})
+	s.server.Close()
+
+	err := s.call(authFunc, "/test.Service/Get", newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{}, s.secret))
+
+	assert.Equal(s.T(), codes.Unavailable, status.Code(err), "must error with unavailable")
+}
+
+func (s *ValidatorTestSuite) TestValidateClaims() {
+	var call jwt.CallInfo
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{
+		SigningKey: s.secret,
+		ValidateClaims: []jwt.ClaimsValidatorFunc{
+			func(ctx context.Context, token *extJwt.Token, info jwt.CallInfo) error {
+				call = info
+				return nil
+			},
+			func(ctx context.Context, token *extJwt.Token, info jwt.CallInfo) error {
+				tenant, _ := token.Claims.(extJwt.MapClaims)["tenant_id"].(string)
+				if got := info.Metadata.Get("x-tenant-id"); len(got) != 1 || got[0] != tenant {
+					return errors.New("tenant mismatch")
+				}
+				return nil
+			},
+		},
+	})
+	token := newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{"tenant_id": "acme"}, s.secret)
+	addr := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 4711}
+	ctx := grpc.NewContextWithServerTransportStream(context.TODO(), &fakeTransportStream{method: "/test.Service/Get"})
+	ctx = peer.NewContext(ctx, &peer.Peer{Addr: addr})
+
+	_, err := authFunc(metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+token, "x-tenant-id", "acme")))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "/test.Service/Get", call.FullMethod)
+	assert.Equal(s.T(), []string{"acme"}, call.Metadata.Get("x-tenant-id"))
+	require.NotNil(s.T(), call.Peer)
+	assert.Equal(s.T(), addr, call.Peer.Addr)
+
+	_, err = authFunc(metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+token, "x-tenant-id", "other")))
+	assert.Equal(s.T(), codes.PermissionDenied, status.Code(err), "must error with permission denied")
+	assert.Equal(s.T(), "tenant mismatch", status.Convert(err).Message())
+}
+
+func (s *ValidatorTestSuite) TestValidateClaimsStatus() {
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{
+		SigningKey: s.secret,
+		ValidateClaims: []jwt.ClaimsValidatorFunc{func(ctx context.Context, token *extJwt.Token, info jwt.CallInfo) error {
+			return status.Error(codes.FailedPrecondition, "account locked")
+		}},
+	})
+
+	err := s.call(authFunc, "/test.Service/Get", newSignedToken(extJwt.SigningMethodHS256, extJwt.MapClaims{}, s.secret))
+
+	assert.Equal(s.T(), codes.FailedPrecondition, status.Code(err), "status must be returned as is")
+}
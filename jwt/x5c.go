@@ -0,0 +1,137 @@
+package jwt
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// X5CConfig defines the config for validating tokens with the certificate chain in their x5c header (RFC 7515).
+type X5CConfig struct {
+	// Roots the certificate chain must lead to.
+	// Required.
+	Roots *x509.CertPool
+
+	// Intermediates used to complete the chain in addition to the certificates of the x5c header.
+	// Optional.
+	Intermediates *x509.CertPool
+
+	// ExtKeyUsages of which the leaf certificate must permit at least one.
+	// Optional. Any extended key usage is accepted if empty.
+	ExtKeyUsages []x509.ExtKeyUsage
+
+	// Subjects of which the leaf certificate's subject must match one, either by its common name or by its
+	// distinguished name, e.g. "CN=partner.example.com,O=Partner".
+	// Optional. The subject is not checked if empty.
+	Subjects []string
+
+	// SANs of which the leaf certificate must carry at least one as DNS name, email address, IP address or URI
+	// subject alternative name.
+	// Optional. The subject alternative names are not checked if empty.
+	SANs []string
+}
+
+// X5CKeySource is a KeySource returning the public key of the leaf certificate in the token's x5c header, after
+// verifying the certificate chain against the configured roots. The leaf certificate must permit digital signatures,
+// if it restricts its key usage. The chain is verified for every token.
+type X5CKeySource struct {
+	config X5CConfig
+}
+
+// NewX5CKeySource returns an X5CKeySource for the given config.
+func NewX5CKeySource(config X5CConfig) (*X5CKeySource, error) {
+	if config.Roots == nil {
+		return nil, errors.New("x5c: missing roots")
+	}
+	if len(config.ExtKeyUsages) == 0 {
+		config.ExtKeyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageAny}
+	}
+	return &X5CKeySource{config: config}, nil
+}
+
+// LookupKey implements KeySource.
+func (source *X5CKeySource) LookupKey(token *jwt.Token) (any, error) {
+	chain, err := parseX5C(token.Header["x5c"])
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid x5c header: %v", err)
+	}
+	leaf := chain[0]
+	if err := source.verify(leaf, chain[1:]); err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid x5c certificate: %v", err)
+	}
+	return leaf.PublicKey, nil
+}
+
+func (source *X5CKeySource) verify(leaf *x509.Certificate, intermediates []*x509.Certificate) error {
+	pool := x509.NewCertPool()
+	if source.config.Intermediates != nil {
+		pool = source.config.Intermediates.Clone()
+	}
+	for _, cert := range intermediates {
+		pool.AddCert(cert)
+	}
+	opts := x509.VerifyOptions{
+		Roots:         source.config.Roots,
+		Intermediates: pool,
+		CurrentTime:   time.Now(),
+		KeyUsages:     source.config.ExtKeyUsages,
+	}
+	if _, err := leaf.Verify(opts); err != nil {
+		return err
+	}
+	if leaf.KeyUsage != 0 && leaf.KeyUsage&x509.KeyUsageDigitalSignature == 0 {
+		return errors.New("key usage does not permit digital signatures")
+	}
+	if len(source.config.Subjects) > 0 && !slices.Contains(source.config.Subjects, leaf.Subject.CommonName) &&
+		!slices.Contains(source.config.Subjects, leaf.Subject.String()) {
+		return errors.New("unexpected subject")
+	}
+	if len(source.config.SANs) > 0 && !slices.ContainsFunc(subjectAltNames(leaf), func(san string) bool {
+		return slices.Contains(source.config.SANs, san)
+	}) {
+		return errors.New("unexpected subject alternative names")
+	}
+	return nil
+}
+
+// parseX5C parses the x5c header into certificates, leaf certificate first.
+func parseX5C(header any) ([]*x509.Certificate, error) {
+	values, ok := header.([]any)
+	if !ok || len(values) == 0 {
+		return nil, errors.New("missing certificates")
+	}
+	chain := make([]*x509.Certificate, 0, len(values))
+	for _, value := range values {
+		s, ok := value.(string)
+		if !ok {
+			return nil, errors.New("certificate is not a string")
+		}
+		der, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, cert)
+	}
+	return chain, nil
+}
+
+func subjectAltNames(cert *x509.Certificate) []string {
+	sans := slices.Concat(cert.DNSNames, cert.EmailAddresses)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	return sans
+}
@@ -0,0 +1,174 @@
+package jwt_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ErenDursun/go-grpc-jwt-middleware/jwt"
+	extJwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newCertificate issues a certificate for template, signed by parent or self-signed if parent is nil.
+func newCertificate(template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template.SerialNumber, _ = rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if template.NotBefore.IsZero() {
+		template.NotBefore = time.Now().Add(-time.Hour)
+	}
+	if template.NotAfter.IsZero() {
+		template.NotAfter = time.Now().Add(time.Hour)
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, _ := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	cert, _ := x509.ParseCertificate(der)
+	return cert, key
+}
+
+// newCACertificate issues a CA certificate signed by parent or self-signed if parent is nil.
+func newCACertificate(name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	return newCertificate(&x509.Certificate{
+		Subject:               pkix.Name{CommonName: name},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, parent, parentKey)
+}
+
+type X5CTestSuite struct {
+	suite.Suite
+
+	root             *x509.Certificate
+	intermediate     *x509.Certificate
+	intermediateKey  *ecdsa.PrivateKey
+	leaf             *x509.Certificate
+	leafKey          *ecdsa.PrivateKey
+	roots            *x509.CertPool
+	claims           extJwt.MapClaims
+	partnerSubject   string
+	partnerSPIFFEURI string
+}
+
+func (s *X5CTestSuite) SetupSuite() {
+	var rootKey *ecdsa.PrivateKey
+	s.root, rootKey = newCACertificate("Test Root CA", nil, nil)
+	s.intermediate, s.intermediateKey = newCACertificate("Test Intermediate CA", s.root, rootKey)
+	s.partnerSubject = "partner.example.com"
+	s.partnerSPIFFEURI = "spiffe://example.com/partner"
+	uri, _ := url.Parse(s.partnerSPIFFEURI)
+	s.leaf, s.leafKey = newCertificate(&x509.Certificate{
+		Subject:     pkix.Name{CommonName: s.partnerSubject, Organization: []string{"Partner"}},
+		DNSNames:    []string{"partner.example.com"},
+		URIs:        []*url.URL{uri},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, s.intermediate, s.intermediateKey)
+	s.roots = x509.NewCertPool()
+	s.roots.AddCert(s.root)
+	s.claims = extJwt.MapClaims{"foo": "bar"}
+}
+
+func TestX5CTestSuite(t *testing.T) {
+	suite.Run(t, new(X5CTestSuite))
+}
+
+// newToken signs a token with key, carrying the given chain in its x5c header.
+func (s *X5CTestSuite) newToken(key *ecdsa.PrivateKey, chain ...*x509.Certificate) string {
+	token := extJwt.NewWithClaims(extJwt.SigningMethodES256, s.claims)
+	if len(chain) > 0 {
+		x5c := make([]string, len(chain))
+		for i, cert := range chain {
+			x5c[i] = base64.StdEncoding.EncodeToString(cert.Raw)
+		}
+		token.Header["x5c"] = x5c
+	}
+	signedToken, _ := token.SignedString(key)
+	return signedToken
+}
+
+func (s *X5CTestSuite) auth(config jwt.X5CConfig, token string) error {
+	source, err := jwt.NewX5CKeySource(config)
+	require.NoError(s.T(), err)
+	authFunc := jwt.NewAuthFuncWithConfig(jwt.Config{KeySource: source, SigningMethods: []string{extJwt.SigningMethodES256.Name}})
+	_, err = authFunc(incomingCtxWithToken(context.TODO(), "Bearer", token))
+	return err
+}
+
+func (s *X5CTestSuite) assertUnauthenticated(err error, msg string) {
+	assert.Equal(s.T(), codes.Unauthenticated, status.Code(err), msg)
+}
+
+func (s *X5CTestSuite) TestValidChain() {
+	require.NoError(s.T(), s.auth(jwt.X5CConfig{Roots: s.roots}, s.newToken(s.leafKey, s.leaf, s.intermediate)))
+
+	intermediates := x509.NewCertPool()
+	intermediates.AddCert(s.intermediate)
+	require.NoError(s.T(), s.auth(jwt.X5CConfig{Roots: s.roots, Intermediates: intermediates}, s.newToken(s.leafKey, s.leaf)),
+		"configured intermediates must complete the chain")
+}
+
+func (s *X5CTestSuite) TestInvalidChain() {
+	s.assertUnauthenticated(s.auth(jwt.X5CConfig{Roots: s.roots}, s.newToken(s.leafKey)), "missing x5c must be rejected")
+	s.assertUnauthenticated(s.auth(jwt.X5CConfig{Roots: s.roots}, s.newToken(s.leafKey, s.leaf)), "incomplete chain must be rejected")
+
+	otherRoot, otherRootKey := newCACertificate("Other Root CA", nil, nil)
+	forged, forgedKey := newCertificate(&x509.Certificate{Subject: pkix.Name{CommonName: s.partnerSubject}}, otherRoot, otherRootKey)
+	s.assertUnauthenticated(s.auth(jwt.X5CConfig{Roots: s.roots}, s.newToken(forgedKey, forged, otherRoot)), "untrusted root must be rejected")
+
+	_, otherKey := newCertificate(&x509.Certificate{}, nil, nil)
+	s.assertUnauthenticated(s.auth(jwt.X5CConfig{Roots: s.roots}, s.newToken(otherKey, s.leaf, s.intermediate)), "token must be signed with the leaf key")
+}
+
+func (s *X5CTestSuite) TestValidity() {
+	expired, expiredKey := newCertificate(&x509.Certificate{
+		Subject:   pkix.Name{CommonName: s.partnerSubject},
+		NotBefore: time.Now().Add(-2 * time.Hour),
+		NotAfter:  time.Now().Add(-time.Hour),
+	}, s.intermediate, s.intermediateKey)
+
+	s.assertUnauthenticated(s.auth(jwt.X5CConfig{Roots: s.roots}, s.newToken(expiredKey, expired, s.intermediate)), "expired certificate must be rejected")
+}
+
+func (s *X5CTestSuite) TestKeyUsage() {
+	encipherment, enciphermentKey := newCertificate(&x509.Certificate{
+		Subject:  pkix.Name{CommonName: s.partnerSubject},
+		KeyUsage: x509.KeyUsageKeyEncipherment,
+	}, s.intermediate, s.intermediateKey)
+	s.assertUnauthenticated(s.auth(jwt.X5CConfig{Roots: s.roots}, s.newToken(enciphermentKey, encipherment, s.intermediate)),
+		"certificate without digital signature key usage must be rejected")
+
+	config := jwt.X5CConfig{Roots: s.roots, ExtKeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}}
+	s.assertUnauthenticated(s.auth(config, s.newToken(s.leafKey, s.leaf, s.intermediate)), "extended key usage must be enforced")
+}
+
+func (s *X5CTestSuite) TestPins() {
+	token := s.newToken(s.leafKey, s.leaf, s.intermediate)
+
+	require.NoError(s.T(), s.auth(jwt.X5CConfig{Roots: s.roots, Subjects: []string{s.partnerSubject}}, token))
+	require.NoError(s.T(), s.auth(jwt.X5CConfig{Roots: s.roots, Subjects: []string{"CN=partner.example.com,O=Partner"}}, token))
+	require.NoError(s.T(), s.auth(jwt.X5CConfig{Roots: s.roots, SANs: []string{s.partnerSPIFFEURI}}, token))
+	require.NoError(s.T(), s.auth(jwt.X5CConfig{Roots: s.roots, SANs: []string{"partner.example.com"}}, token))
+
+	s.assertUnauthenticated(s.auth(jwt.X5CConfig{Roots: s.roots, Subjects: []string{"other.example.com"}}, token), "subject pin must be enforced")
+	s.assertUnauthenticated(s.auth(jwt.X5CConfig{Roots: s.roots, SANs: []string{"spiffe://example.com/other"}}, token), "san pin must be enforced")
+}
+
+func (s *X5CTestSuite) TestMissingRoots() {
+	_, err := jwt.NewX5CKeySource(jwt.X5CConfig{})
+	assert.Error(s.T(), err)
+}